@@ -0,0 +1,25 @@
+package arigo_test
+
+import (
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/siku2/arigo/arigotest"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient starts a fake aria2 server which requires a secret and
+// returns it together with a client connected to it using opts.
+// Both are closed when the test finishes.
+func newTestClient(t *testing.T, opts ...arigo.DialOption) (*arigotest.Server, *arigo.Client) {
+	t.Helper()
+
+	server := arigotest.NewServer("secret")
+	t.Cleanup(server.Close)
+
+	client, err := arigo.Dial(server.URL, server.Secret, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return server, client
+}
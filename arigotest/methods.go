@@ -0,0 +1,1034 @@
+package arigotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/siku2/arigo"
+	"github.com/siku2/arigo/pkg/aria2proto"
+)
+
+// rpcError is the error object of a JSON-RPC response.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+var (
+	errParse          = &rpcError{Code: -32700, Message: "Parse error."}
+	errMethodNotFound = &rpcError{Code: -32601, Message: "Method not found."}
+	errUnauthorized   = &rpcError{Code: 1, Message: "Unauthorized"}
+)
+
+// newError creates an error the way aria2 reports errors of its methods.
+func newError(format string, a ...interface{}) *rpcError {
+	return &rpcError{Code: 1, Message: fmt.Sprintf(format, a...)}
+}
+
+func errGIDNotFound(gid string) *rpcError {
+	return newError("GID %s is not found", gid)
+}
+
+func errMissingParam(i int) *rpcError {
+	return newError("The parameter at %d is required but missing.", i)
+}
+
+var notifications = map[arigo.EventType]string{
+	arigo.StartEvent:      aria2proto.OnDownloadStart,
+	arigo.PauseEvent:      aria2proto.OnDownloadPause,
+	arigo.StopEvent:       aria2proto.OnDownloadStop,
+	arigo.CompleteEvent:   aria2proto.OnDownloadComplete,
+	arigo.BTCompleteEvent: aria2proto.OnBTDownloadComplete,
+	arigo.ErrorEvent:      aria2proto.OnDownloadError,
+}
+
+var methodNames = []string{
+	aria2proto.AddURI, aria2proto.AddTorrent, aria2proto.AddMetalink,
+	aria2proto.Remove, aria2proto.ForceRemove,
+	aria2proto.Pause, aria2proto.PauseAll, aria2proto.ForcePause, aria2proto.ForcePauseAll,
+	aria2proto.Unpause, aria2proto.UnpauseAll,
+	aria2proto.TellStatus, aria2proto.GetURIs, aria2proto.GetFiles, aria2proto.GetPeers, aria2proto.GetServers,
+	aria2proto.TellActive, aria2proto.TellWaiting, aria2proto.TellStopped,
+	aria2proto.ChangePosition, aria2proto.ChangeURI,
+	aria2proto.GetOptions, aria2proto.ChangeOptions, aria2proto.GetGlobalOptions, aria2proto.ChangeGlobalOptions,
+	aria2proto.GetGlobalStats, aria2proto.PurgeDownloadResults, aria2proto.RemoveDownloadResult,
+	aria2proto.GetVersion, aria2proto.GetSessionInfo,
+	aria2proto.Shutdown, aria2proto.ForceShutdown, aria2proto.SaveSession,
+	aria2proto.Multicall, aria2proto.ListMethods, aria2proto.ListNotifications,
+}
+
+var notificationNames = []string{
+	aria2proto.OnDownloadStart,
+	aria2proto.OnDownloadPause,
+	aria2proto.OnDownloadStop,
+	aria2proto.OnDownloadComplete,
+	aria2proto.OnDownloadError,
+	aria2proto.OnBTDownloadComplete,
+}
+
+// call calls the method with the given parameters.
+func (s *Server) call(method string, params []json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case aria2proto.Multicall:
+		return s.multicall(params)
+	case aria2proto.ListMethods:
+		return methodNames, nil
+	case aria2proto.ListNotifications:
+		return notificationNames, nil
+	}
+
+	params, err := s.authorize(params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	switch method {
+	case aria2proto.AddURI:
+		return s.addURI(params)
+	case aria2proto.AddTorrent:
+		return s.addTorrent(params)
+	case aria2proto.AddMetalink:
+		return s.addMetalink(params)
+	case aria2proto.Remove, aria2proto.ForceRemove:
+		return s.remove(params)
+	case aria2proto.Pause, aria2proto.ForcePause:
+		return s.pause(params)
+	case aria2proto.PauseAll, aria2proto.ForcePauseAll:
+		return s.pauseAll()
+	case aria2proto.Unpause:
+		return s.unpause(params)
+	case aria2proto.UnpauseAll:
+		return s.unpauseAll()
+	case aria2proto.TellStatus:
+		return s.tellStatus(params)
+	case aria2proto.GetURIs:
+		return s.getURIs(params)
+	case aria2proto.GetFiles:
+		return s.getFiles(params)
+	case aria2proto.GetPeers:
+		return s.getPeers(params)
+	case aria2proto.GetServers:
+		return s.getServers(params)
+	case aria2proto.TellActive:
+		return s.tellActive(params)
+	case aria2proto.TellWaiting:
+		return s.tellList(s.waiting, params)
+	case aria2proto.TellStopped:
+		return s.tellList(s.stopped, params)
+	case aria2proto.ChangePosition:
+		return s.changePosition(params)
+	case aria2proto.ChangeURI:
+		return s.changeURI(params)
+	case aria2proto.GetOptions:
+		return s.getOption(params)
+	case aria2proto.ChangeOptions:
+		return s.changeOption(params)
+	case aria2proto.GetGlobalOptions:
+		return copyOptions(s.globalOptions), nil
+	case aria2proto.ChangeGlobalOptions:
+		return s.changeGlobalOption(params)
+	case aria2proto.GetGlobalStats:
+		return s.getGlobalStat(), nil
+	case aria2proto.PurgeDownloadResults:
+		return s.purgeDownloadResult(), nil
+	case aria2proto.RemoveDownloadResult:
+		return s.removeDownloadResult(params)
+	case aria2proto.GetVersion:
+		return s.version, nil
+	case aria2proto.GetSessionInfo:
+		return arigo.SessionInfo{ID: s.sessionID}, nil
+	case aria2proto.Shutdown, aria2proto.ForceShutdown, aria2proto.SaveSession:
+		return "OK", nil
+	}
+
+	return nil, errMethodNotFound
+}
+
+// authorize checks the secret token and returns the remaining parameters.
+func (s *Server) authorize(params []json.RawMessage) ([]json.RawMessage, *rpcError) {
+	var token string
+	if len(params) > 0 && json.Unmarshal(params[0], &token) == nil && strings.HasPrefix(token, "token:") {
+		params = params[1:]
+	} else {
+		token = ""
+	}
+
+	if s.Secret != "" && token != "token:"+s.Secret {
+		return nil, errUnauthorized
+	}
+
+	return params, nil
+}
+
+type methodCall struct {
+	MethodName string            `json:"methodName"`
+	Params     []json.RawMessage `json:"params"`
+}
+
+func (s *Server) multicall(params []json.RawMessage) (interface{}, *rpcError) {
+	var calls []methodCall
+	if err := decodeParam(params, 0, &calls); err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(calls))
+	for i, call := range calls {
+		if call.MethodName == aria2proto.Multicall {
+			results[i] = newError("Recursive system.multicall forbidden.")
+			continue
+		}
+
+		result, err := s.call(call.MethodName, call.Params)
+		if err != nil {
+			results[i] = err
+		} else {
+			results[i] = []interface{}{result}
+		}
+	}
+
+	return results, nil
+}
+
+// decodeParam decodes the parameter at index i into v.
+func decodeParam(params []json.RawMessage, i int, v interface{}) *rpcError {
+	if i >= len(params) {
+		return errMissingParam(i)
+	}
+
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return newError("The parameter at %d has wrong type.", i)
+	}
+
+	return nil
+}
+
+// decodeOptions decodes an options object, the values are converted to strings.
+// Arrays are joined with newlines like aria2 does.
+func decodeOptions(data json.RawMessage) (map[string]string, *rpcError) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, newError("options must be a struct: %v", err)
+	}
+
+	options := make(map[string]string, len(raw))
+	for key, value := range raw {
+		// options given multiple times, like header, are reported one per line
+		if list, ok := value.([]interface{}); ok {
+			lines := make([]string, len(list))
+			for i, line := range list {
+				lines[i] = fmt.Sprint(line)
+			}
+			options[key] = strings.Join(lines, "\n")
+			continue
+		}
+
+		options[key] = fmt.Sprint(value)
+	}
+
+	return options, nil
+}
+
+func copyOptions(options map[string]string) map[string]string {
+	c := make(map[string]string, len(options))
+	for key, value := range options {
+		c[key] = value
+	}
+	return c
+}
+
+// decodeAddParams decodes the optional options and position parameters
+// which follow the required parameters of the add methods.
+func decodeAddParams(params []json.RawMessage) (map[string]string, int, *rpcError) {
+	options := map[string]string{}
+	position := -1
+
+	for _, param := range params {
+		switch p := strings.TrimSpace(string(param)); {
+		case strings.HasPrefix(p, "{"):
+			var err *rpcError
+			if options, err = decodeOptions(param); err != nil {
+				return nil, 0, err
+			}
+		case p != "null":
+			if err := json.Unmarshal(param, &position); err != nil {
+				return nil, 0, newError("position must be an integer: %v", err)
+			}
+		}
+	}
+
+	return options, position, nil
+}
+
+// newGID generates a new unique GID.
+// s.mut must be held.
+func (s *Server) newGID() string {
+	s.lastGID++
+	return fmt.Sprintf("%016x", s.lastGID)
+}
+
+// add registers a new download and inserts it into the waiting queue.
+func (s *Server) add(uris []string, params []json.RawMessage) (string, *rpcError) {
+	options, position, err := decodeAddParams(params)
+	if err != nil {
+		return "", err
+	}
+
+	gid := s.newGID()
+
+	dir := options["dir"]
+	if dir == "" {
+		dir = s.globalOptions["dir"]
+	}
+
+	fileURIs := make([]arigo.URI, len(uris))
+	for i, uri := range uris {
+		fileURIs[i] = arigo.URI{URI: uri, Status: arigo.URIWaiting}
+	}
+
+	status := arigo.StatusWaiting
+	if options["pause"] == "true" {
+		status = arigo.StatusPaused
+	}
+
+	s.downloads[gid] = &download{
+		status: arigo.Status{
+			GID:        gid,
+			Status:     status,
+			Dir:        dir,
+			FollowedBy: []string{},
+			Files: []arigo.File{{
+				Index:    1,
+				Selected: true,
+				URIs:     fileURIs,
+			}},
+		},
+		options: options,
+	}
+
+	if s.defaultSim != nil {
+		s.attach(s.downloads[gid], *s.defaultSim)
+	}
+
+	if position < 0 || position > len(s.waiting) {
+		position = len(s.waiting)
+	}
+	s.waiting = insert(s.waiting, position, gid)
+
+	return gid, nil
+}
+
+func (s *Server) addURI(params []json.RawMessage) (interface{}, *rpcError) {
+	var uris []string
+	if err := decodeParam(params, 0, &uris); err != nil {
+		return nil, err
+	}
+
+	if len(uris) == 0 {
+		return nil, newError("URI is not provided.")
+	}
+
+	return s.add(uris, params[1:])
+}
+
+func (s *Server) addTorrent(params []json.RawMessage) (interface{}, *rpcError) {
+	var torrent string
+	if err := decodeParam(params, 0, &torrent); err != nil {
+		return nil, err
+	}
+
+	var uris []string
+	rest := params[1:]
+	if len(rest) > 0 && strings.HasPrefix(strings.TrimSpace(string(rest[0])), "[") {
+		if err := decodeParam(rest, 0, &uris); err != nil {
+			return nil, err
+		}
+		rest = rest[1:]
+	}
+
+	gid, err := s.add(uris, rest)
+	if err != nil {
+		return nil, err
+	}
+
+	s.downloads[gid].status.BitTorrent.Mode = arigo.TorrentModeSingle
+	return gid, nil
+}
+
+func (s *Server) addMetalink(params []json.RawMessage) (interface{}, *rpcError) {
+	var metalink string
+	if err := decodeParam(params, 0, &metalink); err != nil {
+		return nil, err
+	}
+
+	gid, err := s.add(nil, params[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{gid}, nil
+}
+
+// lookup returns the download denoted by the gid parameter at index 0.
+func (s *Server) lookup(params []json.RawMessage) (*download, *rpcError) {
+	var gid string
+	if err := decodeParam(params, 0, &gid); err != nil {
+		return nil, err
+	}
+
+	d, ok := s.downloads[gid]
+	if !ok {
+		return nil, errGIDNotFound(gid)
+	}
+
+	return d, nil
+}
+
+// stop moves the download to the stopped downloads.
+// s.mut must be held.
+func (s *Server) stop(d *download, status arigo.DownloadStatus) {
+	gid := d.status.GID
+	s.active = without(s.active, gid)
+	s.waiting = without(s.waiting, gid)
+	s.stopped = append(s.stopped, gid)
+	s.numStopped++
+
+	d.status.Status = status
+	d.status.DownloadSpeed = 0
+	d.status.UploadSpeed = 0
+	d.status.Connections = 0
+}
+
+func isStopped(status arigo.DownloadStatus) bool {
+	switch status {
+	case arigo.StatusError, arigo.StatusCompleted, arigo.StatusRemoved:
+		return true
+	}
+	return false
+}
+
+func (s *Server) remove(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if isStopped(d.status.Status) {
+		return nil, newError("Active Download not found for GID#%s", d.status.GID)
+	}
+
+	s.stop(d, arigo.StatusRemoved)
+	s.queue(arigo.StopEvent, d.status.GID)
+
+	return d.status.GID, nil
+}
+
+// pauseDownload pauses the download and moves it to the front of the waiting queue
+// if it was active.
+func (s *Server) pauseDownload(d *download) bool {
+	gid := d.status.GID
+
+	switch d.status.Status {
+	case arigo.StatusActive:
+		s.active = without(s.active, gid)
+		s.waiting = insert(s.waiting, 0, gid)
+		d.status.DownloadSpeed = 0
+		d.status.UploadSpeed = 0
+		d.status.Connections = 0
+	case arigo.StatusWaiting:
+	default:
+		return false
+	}
+
+	d.status.Status = arigo.StatusPaused
+	s.queue(arigo.PauseEvent, gid)
+
+	return true
+}
+
+func (s *Server) pause(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.pauseDownload(d) {
+		return nil, newError("GID#%s cannot be paused now", d.status.GID)
+	}
+
+	return d.status.GID, nil
+}
+
+func (s *Server) pauseAll() (interface{}, *rpcError) {
+	// pausing active downloads changes the waiting queue
+	gids := append(append([]string{}, s.active...), s.waiting...)
+	for _, gid := range gids {
+		s.pauseDownload(s.downloads[gid])
+	}
+
+	return "OK", nil
+}
+
+func (s *Server) unpause(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.status.Status != arigo.StatusPaused {
+		return nil, newError("GID#%s cannot be unpaused now", d.status.GID)
+	}
+
+	d.status.Status = arigo.StatusWaiting
+	return d.status.GID, nil
+}
+
+func (s *Server) unpauseAll() (interface{}, *rpcError) {
+	for _, gid := range s.waiting {
+		if d := s.downloads[gid]; d.status.Status == arigo.StatusPaused {
+			d.status.Status = arigo.StatusWaiting
+		}
+	}
+
+	return "OK", nil
+}
+
+// filterKeys encodes the status and only keeps the given keys.
+// All keys are kept if keys is empty.
+func filterKeys(status arigo.Status, keys []string) interface{} {
+	if len(keys) == 0 {
+		return status
+	}
+
+	data, _ := json.Marshal(status)
+
+	var all map[string]json.RawMessage
+	_ = json.Unmarshal(data, &all)
+
+	filtered := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		if value, ok := all[key]; ok {
+			filtered[key] = value
+		}
+	}
+
+	return filtered
+}
+
+// decodeKeys decodes the optional keys parameter at index i.
+func decodeKeys(params []json.RawMessage, i int) ([]string, *rpcError) {
+	var keys []string
+	if i < len(params) {
+		if err := decodeParam(params, i, &keys); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *Server) tellStatus(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := decodeKeys(params, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterKeys(copyStatus(d.status), keys), nil
+}
+
+func (s *Server) getURIs(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	uris := []arigo.URI{}
+	if len(d.status.Files) > 0 {
+		uris = append(uris, d.status.Files[0].URIs...)
+	}
+
+	return uris, nil
+}
+
+func (s *Server) getFiles(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyStatus(d.status).Files, nil
+}
+
+func (s *Server) getPeers(params []json.RawMessage) (interface{}, *rpcError) {
+	if _, err := s.lookup(params); err != nil {
+		return nil, err
+	}
+
+	return []arigo.Peer{}, nil
+}
+
+func (s *Server) getServers(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.status.Status != arigo.StatusActive {
+		return nil, newError("No active download for GID#%s", d.status.GID)
+	}
+
+	return []arigo.FileServers{}, nil
+}
+
+func (s *Server) tellActive(params []json.RawMessage) (interface{}, *rpcError) {
+	keys, err := decodeKeys(params, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.statuses(s.active, keys), nil
+}
+
+func (s *Server) tellList(gids []string, params []json.RawMessage) (interface{}, *rpcError) {
+	var offset, num int
+	if err := decodeParam(params, 0, &offset); err != nil {
+		return nil, err
+	}
+	if err := decodeParam(params, 1, &num); err != nil {
+		return nil, err
+	}
+
+	keys, err := decodeKeys(params, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.statuses(paginate(gids, offset, num), keys), nil
+}
+
+func (s *Server) statuses(gids []string, keys []string) []interface{} {
+	statuses := make([]interface{}, len(gids))
+	for i, gid := range gids {
+		statuses[i] = filterKeys(copyStatus(s.downloads[gid].status), keys)
+	}
+	return statuses
+}
+
+// paginate returns the range of gids the way aria2 does for tellWaiting and tellStopped.
+// A negative offset counts from the end and the range is returned in reverse order.
+func paginate(gids []string, offset, num int) []string {
+	size := len(gids)
+	if num <= 0 {
+		return []string{}
+	}
+
+	if offset < 0 {
+		last := size + offset
+		if last < 0 {
+			return []string{}
+		}
+
+		first := last - (num - 1)
+		if first < 0 {
+			first = 0
+		}
+
+		page := make([]string, 0, last-first+1)
+		for i := last; i >= first; i-- {
+			page = append(page, gids[i])
+		}
+		return page
+	}
+
+	if offset >= size {
+		return []string{}
+	}
+
+	end := offset + num
+	if end > size {
+		end = size
+	}
+
+	return append([]string{}, gids[offset:end]...)
+}
+
+func (s *Server) changePosition(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var pos int
+	if err := decodeParam(params, 1, &pos); err != nil {
+		return nil, err
+	}
+
+	var how string
+	if err := decodeParam(params, 2, &how); err != nil {
+		return nil, err
+	}
+
+	gid := d.status.GID
+	current := indexOf(s.waiting, gid)
+	if current < 0 {
+		return nil, newError("GID#%s not found in the waiting queue.", gid)
+	}
+
+	var dest int
+	switch arigo.PositionSetBehaviour(how) {
+	case arigo.SetPositionStart:
+		dest = pos
+	case arigo.SetPositionRelative:
+		dest = current + pos
+	case arigo.SetPositionEnd:
+		dest = len(s.waiting) - 1 + pos
+	default:
+		return nil, newError("Illegal argument.")
+	}
+
+	if dest < 0 {
+		dest = 0
+	} else if dest >= len(s.waiting) {
+		dest = len(s.waiting) - 1
+	}
+
+	s.waiting = insert(without(s.waiting, gid), dest, gid)
+
+	return dest, nil
+}
+
+func (s *Server) changeURI(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileIndex int
+	var delURIs, addURIs []string
+	if err := decodeParam(params, 1, &fileIndex); err != nil {
+		return nil, err
+	}
+	if err := decodeParam(params, 2, &delURIs); err != nil {
+		return nil, err
+	}
+	if err := decodeParam(params, 3, &addURIs); err != nil {
+		return nil, err
+	}
+
+	position := -1
+	if len(params) > 4 {
+		if err := decodeParam(params, 4, &position); err != nil {
+			return nil, err
+		}
+	}
+
+	if fileIndex < 1 || fileIndex > len(d.status.Files) {
+		return nil, newError("fileIndex is out of range")
+	}
+
+	file := &d.status.Files[fileIndex-1]
+
+	deleted := 0
+	for _, uri := range delURIs {
+		for i, fileURI := range file.URIs {
+			if fileURI.URI == uri {
+				file.URIs = append(file.URIs[:i], file.URIs[i+1:]...)
+				deleted++
+				break
+			}
+		}
+	}
+
+	if position < 0 || position > len(file.URIs) {
+		position = len(file.URIs)
+	}
+
+	added := make([]arigo.URI, len(addURIs))
+	for i, uri := range addURIs {
+		added[i] = arigo.URI{URI: uri, Status: arigo.URIWaiting}
+	}
+
+	uris := append([]arigo.URI{}, file.URIs[:position]...)
+	uris = append(uris, added...)
+	file.URIs = append(uris, file.URIs[position:]...)
+
+	return []int{deleted, len(added)}, nil
+}
+
+func (s *Server) getOption(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	options := copyOptions(s.globalOptions)
+	for key, value := range d.options {
+		options[key] = value
+	}
+
+	return options, nil
+}
+
+func (s *Server) changeOption(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params) < 2 {
+		return nil, errMissingParam(1)
+	}
+
+	options, err := decodeOptions(params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range options {
+		d.options[key] = value
+	}
+
+	return "OK", nil
+}
+
+func (s *Server) changeGlobalOption(params []json.RawMessage) (interface{}, *rpcError) {
+	if len(params) < 1 {
+		return nil, errMissingParam(0)
+	}
+
+	options, err := decodeOptions(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range options {
+		s.globalOptions[key] = value
+	}
+
+	return "OK", nil
+}
+
+func (s *Server) getGlobalStat() arigo.Stats {
+	stats := arigo.Stats{
+		NumActive:       uint(len(s.active)),
+		NumWaiting:      uint(len(s.waiting)),
+		NumStopped:      uint(len(s.stopped)),
+		NumStoppedTotal: s.numStopped,
+	}
+
+	for _, gid := range s.active {
+		status := s.downloads[gid].status
+		stats.DownloadSpeed += status.DownloadSpeed
+		stats.UploadSpeed += status.UploadSpeed
+	}
+
+	return stats
+}
+
+func (s *Server) purgeDownloadResult() interface{} {
+	for _, gid := range s.stopped {
+		delete(s.downloads, gid)
+	}
+	s.stopped = nil
+
+	return "OK"
+}
+
+func (s *Server) removeDownloadResult(params []json.RawMessage) (interface{}, *rpcError) {
+	d, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+
+	gid := d.status.GID
+	if !isStopped(d.status.Status) {
+		return nil, newError("Could not remove download result of GID#%s", gid)
+	}
+
+	s.stopped = without(s.stopped, gid)
+	delete(s.downloads, gid)
+
+	return "OK", nil
+}
+
+func indexOf(gids []string, gid string) int {
+	for i, g := range gids {
+		if g == gid {
+			return i
+		}
+	}
+	return -1
+}
+
+func without(gids []string, gid string) []string {
+	i := indexOf(gids, gid)
+	if i < 0 {
+		return gids
+	}
+	return append(gids[:i:i], gids[i+1:]...)
+}
+
+func insert(gids []string, i int, gid string) []string {
+	result := make([]string, 0, len(gids)+1)
+	result = append(result, gids[:i]...)
+	result = append(result, gid)
+	return append(result, gids[i:]...)
+}
+
+// Downloads returns the GIDs of all downloads known to the server, in no particular order.
+func (s *Server) Downloads() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	gids := make([]string, 0, len(s.downloads))
+	for gid := range s.downloads {
+		gids = append(gids, gid)
+	}
+	sort.Strings(gids)
+
+	return gids
+}
+
+// Status returns the status of the download denoted by gid.
+// The second return value reports whether the download exists.
+func (s *Server) Status(gid string) (arigo.Status, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	d, ok := s.downloads[gid]
+	if !ok {
+		return arigo.Status{}, false
+	}
+
+	return copyStatus(d.status), true
+}
+
+// copyStatus copies the status including its files,
+// so that it can be used after s.mut was released.
+func copyStatus(status arigo.Status) arigo.Status {
+	status.FollowedBy = append([]string{}, status.FollowedBy...)
+
+	files := make([]arigo.File, len(status.Files))
+	for i, file := range status.Files {
+		file.URIs = append([]arigo.URI{}, file.URIs...)
+		files[i] = file
+	}
+	status.Files = files
+
+	return status
+}
+
+// Update calls update with the status of the download denoted by gid,
+// which allows tests to set arbitrary fields like TotalLength.
+// The queue position of the download isn't changed, use the other methods
+// to change its Status.
+// It returns false if the download doesn't exist.
+func (s *Server) Update(gid string, update func(status *arigo.Status)) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	d, ok := s.downloads[gid]
+	if !ok {
+		return false
+	}
+
+	update(&d.status)
+	d.status.GID = gid
+
+	return true
+}
+
+// StartDownload moves the waiting download denoted by gid to the active downloads
+// and sends the onDownloadStart notification.
+func (s *Server) StartDownload(gid string) error {
+	return s.transition(gid, func(d *download) *rpcError {
+		if d.status.Status != arigo.StatusWaiting {
+			return newError("GID#%s cannot be started now", gid)
+		}
+
+		s.waiting = without(s.waiting, gid)
+		s.active = append(s.active, gid)
+		d.status.Status = arigo.StatusActive
+		if d.sim != nil {
+			d.status.DownloadSpeed = arigo.Rate(d.sim.Speed)
+			d.status.Connections = d.sim.Connections
+		}
+		s.queue(arigo.StartEvent, gid)
+
+		return nil
+	})
+}
+
+// CompleteDownload completes the download denoted by gid
+// and sends the onDownloadComplete notification.
+// The completed length of the download and its files is set to their total length.
+func (s *Server) CompleteDownload(gid string) error {
+	return s.transition(gid, func(d *download) *rpcError {
+		if isStopped(d.status.Status) {
+			return newError("GID#%s is already stopped", gid)
+		}
+
+		d.status.CompletedLength = d.status.TotalLength
+		for i := range d.status.Files {
+			d.status.Files[i].CompletedLength = d.status.Files[i].Length
+		}
+
+		s.stop(d, arigo.StatusCompleted)
+		s.queue(arigo.CompleteEvent, gid)
+
+		return nil
+	})
+}
+
+// FailDownload stops the download denoted by gid with an error
+// and sends the onDownloadError notification.
+func (s *Server) FailDownload(gid string, code arigo.ExitStatus, message string) error {
+	return s.transition(gid, func(d *download) *rpcError {
+		if isStopped(d.status.Status) {
+			return newError("GID#%s is already stopped", gid)
+		}
+
+		d.status.ErrorCode = code
+		d.status.ErrorMessage = message
+
+		s.stop(d, arigo.StatusError)
+		s.queue(arigo.ErrorEvent, gid)
+
+		return nil
+	})
+}
+
+// transition applies the change to the download denoted by gid
+// and sends the queued notifications.
+func (s *Server) transition(gid string, change func(d *download) *rpcError) error {
+	s.mut.Lock()
+
+	d, ok := s.downloads[gid]
+	if !ok {
+		s.mut.Unlock()
+		return errGIDNotFound(gid)
+	}
+
+	err := change(d)
+	s.mut.Unlock()
+
+	s.flush()
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
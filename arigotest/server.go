@@ -0,0 +1,308 @@
+// Package arigotest provides a fake aria2 RPC server for testing code built on arigo.
+//
+// The server speaks the aria2 JSON-RPC protocol over WebSocket as well as plain HTTP.
+// Downloads are kept in memory and never actually transferred.
+// Instead, tests drive them using methods like StartDownload and CompleteDownload,
+// which also send the corresponding notifications to all connected clients.
+//
+// Alternatively, a Simulation can be attached to downloads, which then progress
+// deterministically whenever the server's virtual clock is advanced using Advance.
+package arigotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/siku2/arigo"
+)
+
+// Server is a fake aria2 RPC server.
+type Server struct {
+	URL     string // WebSocket url of the JSON-RPC endpoint, ws://ipaddr:port/jsonrpc
+	HTTPURL string // HTTP url of the JSON-RPC endpoint, http://ipaddr:port/jsonrpc
+	Secret  string // Secret authorization token, empty if none is required
+
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	mut           sync.Mutex
+	downloads     map[string]*download
+	active        []string // GIDs of active downloads
+	waiting       []string // GIDs of waiting and paused downloads in queue order
+	stopped       []string // GIDs of stopped downloads in the order they stopped
+	numStopped    uint     // total number of downloads stopped in this session
+	lastGID       uint64
+	globalOptions map[string]string
+	version       arigo.VersionInfo
+	sessionID     string
+	defaultSim    *Simulation
+	elapsed       time.Duration // total time the virtual clock was advanced by
+
+	// notifications which are sent once the current request was answered
+	queued []notification
+
+	connMut sync.Mutex
+	conns   map[*conn]struct{}
+}
+
+type notification struct {
+	evtType arigo.EventType
+	gid     string
+}
+
+type download struct {
+	status  arigo.Status
+	options map[string]string
+	sim     *Simulation   // nil if the download isn't simulated
+	seeded  time.Duration // time the simulated download has been seeding
+}
+
+// NewServer starts and returns a new Server.
+// If secret isn't empty, clients must use it as their authToken.
+// The caller should call Close when finished, to shut it down.
+func NewServer(secret string) *Server {
+	s := &Server{
+		Secret:        secret,
+		downloads:     make(map[string]*download),
+		globalOptions: map[string]string{"dir": "/downloads"},
+		version: arigo.VersionInfo{
+			Version:         "1.36.0",
+			EnabledFeatures: []string{"Async DNS", "BitTorrent", "Metalink"},
+		},
+		sessionID: "cd6a3bc6a1de28eb5bfa181e5f6b916d44af31a9",
+		conns:     make(map[*conn]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jsonrpc", s.serveHTTP)
+	s.server = httptest.NewServer(mux)
+
+	s.HTTPURL = s.server.URL + "/jsonrpc"
+	s.URL = "ws" + strings.TrimPrefix(s.HTTPURL, "http")
+
+	return s
+}
+
+// Close closes all connections and shuts down the server.
+func (s *Server) Close() {
+	s.connMut.Lock()
+	for c := range s.conns {
+		_ = c.ws.Close()
+	}
+	s.connMut.Unlock()
+
+	s.server.Close()
+}
+
+// Client creates a new client connected to the server over WebSocket.
+func (s *Server) Client() (*arigo.Client, error) {
+	return arigo.DialContext(context.Background(), s.URL, s.Secret)
+}
+
+// SetVersion sets the version information returned by aria2.getVersion.
+func (s *Server) SetVersion(version arigo.VersionInfo) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.version = version
+}
+
+type conn struct {
+	ws  *websocket.Conn
+	mut sync.Mutex // protects writes to ws
+}
+
+func (c *conn) write(data []byte) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.ws.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json-rpc")
+	_, _ = w.Write(s.handleMessage(body))
+
+	s.flush()
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &conn{ws: ws}
+
+	s.connMut.Lock()
+	s.conns[c] = struct{}{}
+	s.connMut.Unlock()
+
+	defer func() {
+		s.connMut.Lock()
+		delete(s.conns, c)
+		s.connMut.Unlock()
+
+		_ = ws.Close()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		err = c.write(s.handleMessage(data))
+		s.flush()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+type request struct {
+	ID     *json.RawMessage  `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	ID     *json.RawMessage
+	Result interface{}
+	Error  *rpcError
+}
+
+// MarshalJSON encodes the response, it only includes the result
+// if there's no error.
+func (r response) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string           `json:"jsonrpc"`
+			ID      *json.RawMessage `json:"id"`
+			Error   *rpcError        `json:"error"`
+		}{"2.0", r.ID, r.Error})
+	}
+
+	return json.Marshal(struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Result  interface{}      `json:"result"`
+	}{"2.0", r.ID, r.Result})
+}
+
+// handleMessage handles a single request or a batch of requests
+// and returns the encoded response.
+func (s *Server) handleMessage(data []byte) []byte {
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '[' {
+		var reqs []request
+		if err := json.Unmarshal(data, &reqs); err != nil {
+			return encodeResponse(response{Error: errParse})
+		}
+
+		resps := make([]response, len(reqs))
+		for i, req := range reqs {
+			resps[i] = s.handleRequest(req)
+		}
+
+		out, _ := json.Marshal(resps)
+		return out
+	}
+
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return encodeResponse(response{Error: errParse})
+	}
+
+	return encodeResponse(s.handleRequest(req))
+}
+
+func (s *Server) handleRequest(req request) response {
+	resp := response{ID: req.ID}
+
+	result, err := s.call(req.Method, req.Params)
+	if err != nil {
+		resp.Error = err
+	} else {
+		resp.Result = result
+	}
+
+	return resp
+}
+
+func encodeResponse(resp response) []byte {
+	out, _ := json.Marshal(resp)
+	return out
+}
+
+// queue queues the notification for the event.
+// s.mut must be held.
+func (s *Server) queue(evtType arigo.EventType, gid string) {
+	s.queued = append(s.queued, notification{evtType, gid})
+}
+
+// flush sends all queued notifications.
+func (s *Server) flush() {
+	s.mut.Lock()
+	queued := s.queued
+	s.queued = nil
+	s.mut.Unlock()
+
+	for _, n := range queued {
+		s.Notify(n.evtType, n.gid)
+	}
+}
+
+// Notify sends the notification for the given event to all clients
+// connected over WebSocket.
+// The methods driving downloads, such as CompleteDownload, already send their
+// notifications, Notify can be used to send additional ones.
+func (s *Server) Notify(evtType arigo.EventType, gid string) {
+	method, ok := notifications[evtType]
+	if !ok {
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  []interface{}{map[string]string{"gid": gid}},
+	})
+
+	s.connMut.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.connMut.Unlock()
+
+	for _, c := range conns {
+		_ = c.write(data)
+	}
+}
@@ -0,0 +1,150 @@
+package arigotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerDownload(t *testing.T) {
+	server := NewServer("secret")
+	defer server.Close()
+
+	client, err := server.Client()
+	require.NoError(t, err)
+	defer client.Close()
+
+	gid, err := client.AddURI([]string{"https://example.org/file"}, &arigo.Options{Dir: "/tmp"})
+	require.NoError(t, err)
+
+	status, err := gid.TellStatus()
+	require.NoError(t, err)
+	assert.Equal(t, arigo.StatusWaiting, status.Status)
+	assert.Equal(t, "/tmp", status.Dir)
+	assert.Equal(t, "https://example.org/file", status.Files[0].URIs[0].URI)
+
+	started := make(chan string, 1)
+	client.Subscribe(arigo.StartEvent, func(event *arigo.DownloadEvent) {
+		started <- event.GID
+	})
+
+	require.NoError(t, server.StartDownload(gid.GID))
+	select {
+	case g := <-started:
+		assert.Equal(t, gid.GID, g)
+	case <-time.After(time.Second):
+		t.Fatal("didn't receive start event")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- gid.WaitForDownload()
+	}()
+
+	// give WaitForDownload time to subscribe
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, server.CompleteDownload(gid.GID))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("WaitForDownload didn't return")
+	}
+
+	stats, err := client.GetGlobalStats()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), stats.NumStopped)
+}
+
+func TestServerQueue(t *testing.T) {
+	server := NewServer("")
+	defer server.Close()
+
+	client, err := server.Client()
+	require.NoError(t, err)
+	defer client.Close()
+
+	var gids []string
+	for i := 0; i < 4; i++ {
+		gid, err := client.AddURI([]string{"https://example.org"}, nil)
+		require.NoError(t, err)
+		gids = append(gids, gid.GID)
+	}
+
+	pos, err := client.ChangePosition(gids[3], 0, arigo.SetPositionStart)
+	require.NoError(t, err)
+	assert.Equal(t, 0, pos)
+
+	waiting, err := client.TellWaiting(0, 10, "gid")
+	require.NoError(t, err)
+	assert.Equal(t, []string{gids[3], gids[0], gids[1], gids[2]}, statusGIDs(waiting))
+
+	waiting, err = client.TellWaiting(-1, 2, "gid")
+	require.NoError(t, err)
+	assert.Equal(t, []string{gids[2], gids[1]}, statusGIDs(waiting))
+
+	require.NoError(t, client.Pause(gids[1]))
+	status, ok := server.Status(gids[1])
+	require.True(t, ok)
+	assert.Equal(t, arigo.StatusPaused, status.Status)
+
+	require.NoError(t, client.Remove(gids[0]))
+	stopped, err := client.TellStopped(0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{gids[0]}, statusGIDs(stopped))
+	assert.Equal(t, arigo.StatusRemoved, stopped[0].Status)
+}
+
+func TestServerErrors(t *testing.T) {
+	server := NewServer("secret")
+	defer server.Close()
+
+	call := func(body string) map[string]interface{} {
+		resp, err := http.Post(server.HTTPURL, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var reply map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&reply))
+		return reply
+	}
+
+	reply := call(`{"jsonrpc":"2.0","id":1,"method":"aria2.getVersion","params":["token:wrong"]}`)
+	assert.Equal(t, map[string]interface{}{"code": 1.0, "message": "Unauthorized"}, reply["error"])
+
+	reply = call(`{"jsonrpc":"2.0","id":2,"method":"aria2.tellStatus","params":["token:secret","0000000000000001"]}`)
+	assert.Equal(t, "GID 0000000000000001 is not found", reply["error"].(map[string]interface{})["message"])
+
+	reply = call(`{"jsonrpc":"2.0","id":3,"method":"system.multicall","params":[[` +
+		`{"methodName":"aria2.getVersion","params":["token:secret"]},` +
+		`{"methodName":"aria2.getVersion","params":[]}]]}`)
+	results := reply["result"].([]interface{})
+	assert.Equal(t, "1.36.0", results[0].([]interface{})[0].(map[string]interface{})["version"])
+	assert.Equal(t, "Unauthorized", results[1].(map[string]interface{})["message"])
+}
+
+func TestPaginate(t *testing.T) {
+	gids := []string{"a", "b", "c", "d"}
+
+	assert.Equal(t, []string{"b", "c"}, paginate(gids, 1, 2))
+	assert.Equal(t, []string{"d"}, paginate(gids, 3, 5))
+	assert.Equal(t, []string{}, paginate(gids, 4, 1))
+	assert.Equal(t, []string{"d", "c", "b"}, paginate(gids, -1, 3))
+	assert.Equal(t, []string{"b", "a"}, paginate(gids, -3, 5))
+	assert.Equal(t, []string{}, paginate(gids, -5, 1))
+}
+
+func statusGIDs(statuses []arigo.Status) []string {
+	gids := make([]string, len(statuses))
+	for i, status := range statuses {
+		gids[i] = status.GID
+	}
+	return gids
+}
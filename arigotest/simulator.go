@@ -0,0 +1,317 @@
+package arigotest
+
+import (
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/siku2/arigo"
+)
+
+// DefaultPieceLength is the piece length of simulated downloads
+// if Simulation.PieceLength is not set.
+const DefaultPieceLength = 1024 * 1024
+
+// defaultMaxConcurrentDownloads is aria2's default for max-concurrent-downloads.
+const defaultMaxConcurrentDownloads = 5
+
+// Simulation describes how a download progresses when the server's virtual clock
+// is advanced using Advance.
+type Simulation struct {
+	TotalLength uint // Total length of the download in bytes
+	Speed       uint // Download speed in bytes/sec
+	Connections uint // Number of connections reported while the download is active
+	PieceLength uint // Piece length in bytes, if zero DefaultPieceLength is used
+
+	// If ExitStatus isn't Success, the download fails with it once its
+	// completed length reaches FailAt.
+	ExitStatus   arigo.ExitStatus
+	ErrorMessage string
+	FailAt       uint
+
+	// BitTorrent causes the onBtDownloadComplete notification to be sent
+	// before the onDownloadComplete notification.
+	BitTorrent bool
+
+	// SeedTime is the time a BitTorrent download keeps seeding after it
+	// finished downloading. While seeding, the download stays active with
+	// Seeder set and only completes once it seeded for SeedTime,
+	// which is counted from the next call to Advance.
+	// If zero, the download completes right away.
+	SeedTime time.Duration
+
+	// FollowedBy contains the simulations of the downloads which are
+	// generated when the download completes.
+	// For example, a download of a torrent file is followed by the download
+	// of the torrent's content.
+	// The new downloads are appended to the waiting queue and listed in
+	// the FollowedBy field of the status.
+	FollowedBy []Simulation
+}
+
+func (sim *Simulation) pieceLength() uint {
+	if sim.PieceLength == 0 {
+		return DefaultPieceLength
+	}
+	return sim.PieceLength
+}
+
+// numPieces returns the number of pieces of the download.
+func (sim *Simulation) numPieces() uint {
+	pieceLength := sim.pieceLength()
+	return (sim.TotalLength + pieceLength - 1) / pieceLength
+}
+
+// bitField returns the hexadecimal bitfield of the pieces which are
+// completely loaded.
+func (sim *Simulation) bitField(completedLength uint) string {
+	numPieces := sim.numPieces()
+	if numPieces == 0 {
+		return ""
+	}
+
+	loaded := completedLength / sim.pieceLength()
+	if completedLength == sim.TotalLength {
+		loaded = numPieces
+	}
+
+	bits := make([]byte, (numPieces+7)/8)
+	for i := uint(0); i < loaded; i++ {
+		bits[i/8] |= 0x80 >> (i % 8)
+	}
+
+	return hex.EncodeToString(bits)
+}
+
+// Simulate attaches the simulation to the download denoted by gid.
+// The download is started, progressed and stopped according to it
+// when the virtual clock is advanced using Advance.
+//
+// Downloads without a simulation are never started by Advance.
+func (s *Server) Simulate(gid string, sim Simulation) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	d, ok := s.downloads[gid]
+	if !ok {
+		return errGIDNotFound(gid)
+	}
+
+	s.attach(d, sim)
+	return nil
+}
+
+// SetDefaultSimulation sets the simulation which is attached to all downloads
+// added using the RPC interface from now on.
+// Passing nil stops attaching simulations.
+func (s *Server) SetDefaultSimulation(sim *Simulation) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.defaultSim = sim
+}
+
+// attach attaches the simulation to the download.
+// s.mut must be held.
+func (s *Server) attach(d *download, sim Simulation) {
+	d.sim = &sim
+
+	status := &d.status
+	status.TotalLength = sim.TotalLength
+	status.PieceLength = sim.pieceLength()
+	status.NumPieces = sim.numPieces()
+	if status.CompletedLength > sim.TotalLength {
+		status.CompletedLength = sim.TotalLength
+	}
+	status.BitField = sim.bitField(status.CompletedLength)
+
+	if len(status.Files) > 0 {
+		status.Files[0].Length = sim.TotalLength
+		status.Files[0].CompletedLength = status.CompletedLength
+	}
+}
+
+// Elapsed returns the time the virtual clock was advanced by in total.
+func (s *Server) Elapsed() time.Duration {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.elapsed
+}
+
+// Advance advances the virtual clock by d and updates all simulated downloads.
+//
+// First, waiting downloads with a simulation are started in queue order
+// as long as there are less active downloads than the max-concurrent-downloads
+// global option allows.
+// Then every active simulated download is progressed by d at its speed,
+// after which it's completed or failed if it reached the end of its simulation.
+// BitTorrent downloads with a SeedTime start seeding instead of completing.
+// Downloads started by a completed download only progress in the next call.
+//
+// The notifications of all changes are sent before Advance returns.
+func (s *Server) Advance(d time.Duration) {
+	s.mut.Lock()
+
+	s.elapsed += d
+	s.startWaiting()
+
+	for _, gid := range append([]string{}, s.active...) {
+		if dl := s.downloads[gid]; dl.sim != nil {
+			s.progress(dl, d)
+		}
+	}
+
+	s.mut.Unlock()
+
+	s.flush()
+}
+
+// startWaiting starts waiting simulated downloads until the active downloads are full.
+// s.mut must be held.
+func (s *Server) startWaiting() {
+	max, err := strconv.Atoi(s.globalOptions["max-concurrent-downloads"])
+	if err != nil || max <= 0 {
+		max = defaultMaxConcurrentDownloads
+	}
+
+	for _, gid := range append([]string{}, s.waiting...) {
+		if len(s.active) >= max {
+			return
+		}
+
+		d := s.downloads[gid]
+		if d.sim == nil || d.status.Status != arigo.StatusWaiting {
+			continue
+		}
+
+		s.waiting = without(s.waiting, gid)
+		s.active = append(s.active, gid)
+		d.status.Status = arigo.StatusActive
+		if !d.status.Seeder {
+			d.status.DownloadSpeed = arigo.Rate(d.sim.Speed)
+		}
+		d.status.Connections = d.sim.Connections
+		s.queue(arigo.StartEvent, gid)
+	}
+}
+
+// progress progresses the active download by elapsed.
+// s.mut must be held.
+func (s *Server) progress(d *download, elapsed time.Duration) {
+	sim := d.sim
+	status := &d.status
+
+	if status.Seeder {
+		s.seed(d, elapsed)
+		return
+	}
+
+	end := uint64(sim.TotalLength)
+	failing := sim.ExitStatus != arigo.Success && uint64(sim.FailAt) <= end
+	if failing {
+		end = uint64(sim.FailAt)
+	}
+
+	completed := uint64(status.CompletedLength)
+	if completed < end {
+		completed += transferred(uint64(sim.Speed), elapsed, end-completed)
+	} else {
+		completed = end
+	}
+
+	status.CompletedLength = uint(completed)
+	status.BitField = sim.bitField(status.CompletedLength)
+	if len(status.Files) > 0 {
+		status.Files[0].CompletedLength = status.CompletedLength
+	}
+
+	if completed < end {
+		return
+	}
+
+	gid := status.GID
+	if failing {
+		status.ErrorCode = sim.ExitStatus
+		status.ErrorMessage = sim.ErrorMessage
+		s.stop(d, arigo.StatusError)
+		s.queue(arigo.ErrorEvent, gid)
+		return
+	}
+
+	for _, followerSim := range sim.FollowedBy {
+		follower := s.follow(d)
+		s.attach(follower, followerSim)
+	}
+
+	if sim.BitTorrent {
+		s.queue(arigo.BTCompleteEvent, gid)
+
+		if sim.SeedTime > 0 {
+			status.Seeder = true
+			status.DownloadSpeed = 0
+			return
+		}
+	}
+
+	s.stop(d, arigo.StatusCompleted)
+	s.queue(arigo.CompleteEvent, gid)
+}
+
+// seed completes the seeding download once it seeded for SeedTime.
+// s.mut must be held.
+func (s *Server) seed(d *download, elapsed time.Duration) {
+	d.seeded += elapsed
+	if d.seeded < d.sim.SeedTime {
+		return
+	}
+
+	s.stop(d, arigo.StatusCompleted)
+	s.queue(arigo.CompleteEvent, d.status.GID)
+}
+
+// transferred returns the number of bytes transferred at speed bytes/sec
+// within elapsed, but at most max.
+func transferred(speed uint64, elapsed time.Duration, max uint64) uint64 {
+	if elapsed <= 0 {
+		return 0
+	}
+
+	// speed * elapsed easily exceeds 64 bits, so it's computed with 128 bits
+	hi, lo := bits.Mul64(speed, uint64(elapsed))
+	if hi >= uint64(time.Second) {
+		// the quotient doesn't fit into 64 bits
+		return max
+	}
+
+	n, _ := bits.Div64(hi, lo, uint64(time.Second))
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// follow creates a download which is generated by the given download.
+// s.mut must be held.
+func (s *Server) follow(d *download) *download {
+	gid := s.newGID()
+
+	follower := &download{
+		status: arigo.Status{
+			GID:        gid,
+			Status:     arigo.StatusWaiting,
+			Dir:        d.status.Dir,
+			FollowedBy: []string{},
+			Following:  d.status.GID,
+			Files:      []arigo.File{{Index: 1, Selected: true, URIs: []arigo.URI{}}},
+		},
+		options: copyOptions(d.options),
+	}
+
+	s.downloads[gid] = follower
+	s.waiting = append(s.waiting, gid)
+	d.status.FollowedBy = append(d.status.FollowedBy, gid)
+
+	return follower
+}
@@ -0,0 +1,141 @@
+package arigotest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulationBitField(t *testing.T) {
+	sim := Simulation{TotalLength: 10, PieceLength: 1}
+
+	assert.Equal(t, "0000", sim.bitField(0))
+	assert.Equal(t, "e000", sim.bitField(3))
+	assert.Equal(t, "ff80", sim.bitField(9))
+	assert.Equal(t, "ffc0", sim.bitField(10))
+	assert.Equal(t, "", (&Simulation{}).bitField(0))
+}
+
+func TestServerAdvance(t *testing.T) {
+	server := NewServer("")
+	defer server.Close()
+
+	client, err := server.Client()
+	require.NoError(t, err)
+	defer client.Close()
+
+	server.SetDefaultSimulation(&Simulation{
+		TotalLength: 4096,
+		Speed:       1024,
+		Connections: 2,
+		PieceLength: 1024,
+		FollowedBy:  []Simulation{{TotalLength: 100, Speed: 100}},
+	})
+
+	gid, err := client.AddURI([]string{"magnet:?xt=urn:btih:248d0a1cd08284299de78d5c1ed359bb46717d8c"}, nil)
+	require.NoError(t, err)
+
+	failing, err := client.AddURI([]string{"https://example.org"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, server.Simulate(failing.GID, Simulation{
+		TotalLength:  1000,
+		Speed:        500,
+		FailAt:       600,
+		ExitStatus:   arigo.NetworkError,
+		ErrorMessage: "connection reset",
+	}))
+
+	server.Advance(1500 * time.Millisecond)
+	assert.Equal(t, 1500*time.Millisecond, server.Elapsed())
+
+	status, err := gid.TellStatus()
+	require.NoError(t, err)
+	assert.Equal(t, arigo.StatusActive, status.Status)
+	assert.Equal(t, uint(1536), status.CompletedLength)
+	assert.Equal(t, arigo.Rate(1024), status.DownloadSpeed)
+	assert.Equal(t, uint(2), status.Connections)
+	assert.Equal(t, "80", status.BitField)
+
+	server.Advance(3 * time.Second)
+
+	status, err = gid.TellStatus()
+	require.NoError(t, err)
+	assert.Equal(t, arigo.StatusCompleted, status.Status)
+	assert.Equal(t, uint(4096), status.CompletedLength)
+	assert.Equal(t, "f0", status.BitField)
+	require.Len(t, status.FollowedBy, 1)
+
+	failed, err := failing.TellStatus()
+	require.NoError(t, err)
+	assert.Equal(t, arigo.StatusError, failed.Status)
+	assert.Equal(t, uint(600), failed.CompletedLength)
+	assert.Equal(t, arigo.NetworkError, failed.ErrorCode)
+	assert.Equal(t, "connection reset", failed.ErrorMessage)
+
+	follower, ok := server.Status(status.FollowedBy[0])
+	require.True(t, ok)
+	assert.Equal(t, arigo.StatusWaiting, follower.Status)
+	assert.Equal(t, gid.GID, follower.Following)
+
+	server.Advance(time.Second)
+
+	follower, _ = server.Status(status.FollowedBy[0])
+	assert.Equal(t, arigo.StatusCompleted, follower.Status)
+}
+
+func TestTransferred(t *testing.T) {
+	assert.Equal(t, uint64(1500), transferred(1000, 1500*time.Millisecond, 10000))
+	assert.Equal(t, uint64(100), transferred(1000, time.Second, 100))
+	assert.Equal(t, uint64(0), transferred(1000, -time.Second, 100))
+
+	// the product of speed and elapsed overflows 64 bits
+	max := ^uint64(0)
+	assert.Equal(t, max, transferred(max, time.Hour, max))
+	assert.Equal(t, uint64(1)<<40*3600, transferred(1<<40, time.Hour, max))
+}
+
+func TestServerSeeding(t *testing.T) {
+	server := NewServer("")
+	defer server.Close()
+
+	client, err := server.Client()
+	require.NoError(t, err)
+	defer client.Close()
+
+	server.SetDefaultSimulation(&Simulation{
+		TotalLength: 1000,
+		Speed:       1000,
+		BitTorrent:  true,
+		SeedTime:    2 * time.Second,
+	})
+
+	btComplete := make(chan string, 1)
+	client.Subscribe(arigo.BTCompleteEvent, func(event *arigo.DownloadEvent) {
+		btComplete <- event.GID
+	})
+
+	gid, err := client.AddURI([]string{"magnet:?xt=urn:btih:248d0a1cd08284299de78d5c1ed359bb46717d8c"}, nil)
+	require.NoError(t, err)
+
+	server.Advance(time.Second)
+	assert.Equal(t, gid.GID, <-btComplete)
+
+	status, err := gid.TellStatus()
+	require.NoError(t, err)
+	assert.Equal(t, arigo.StatusActive, status.Status)
+	assert.True(t, status.Seeder)
+	assert.Equal(t, uint(1000), status.CompletedLength)
+	assert.Equal(t, arigo.Rate(0), status.DownloadSpeed)
+
+	server.Advance(time.Second)
+	status, _ = server.Status(gid.GID)
+	assert.Equal(t, arigo.StatusActive, status.Status)
+
+	server.Advance(time.Second)
+	status, _ = server.Status(gid.GID)
+	assert.Equal(t, arigo.StatusCompleted, status.Status)
+	assert.True(t, status.Seeder)
+}
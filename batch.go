@@ -0,0 +1,398 @@
+package arigo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/siku2/arigo/pkg/aria2proto"
+)
+
+// errMissingResult is the error of a call aria2 didn't return a result for.
+var errMissingResult = errors.New("missing result")
+
+// BatchResult is the result of a call of a Batch.
+type BatchResult struct {
+	Method string // Name of the called method
+
+	// Value is the decoded result of the call, its type matches the
+	// result of the corresponding Client method. For example, it's a GID
+	// for AddURI and a Status for TellStatus.
+	// It's nil for methods which only return an error or if the call failed.
+	Value interface{}
+
+	// Err is the error of the call or nil if it succeeded.
+	// Errors reported by aria2 are a *MethodCallError.
+	Err error
+}
+
+// decodeFunc decodes the raw result of a call.
+type decodeFunc func(c *Client, raw []byte) (interface{}, error)
+
+// Batch collects calls which are sent to aria2 in a single system.multicall
+// request using Do. Its methods mirror the ones of the Client and can be chained:
+//
+//	results, err := client.Batch().
+//		AddURI([]string{"https://example.org/file"}, nil).
+//		TellStatus(gid, "status").
+//		Do()
+//
+// A Batch must not be used concurrently.
+type Batch struct {
+	client   *Client
+	calls    []*MethodCall
+	decoders []decodeFunc
+	errs     []error  // errors of the calls which are invalid and aren't sent
+	features []string // features required by the calls, empty if none is
+}
+
+// Batch creates a new, empty batch of calls.
+func (c *Client) Batch() *Batch {
+	return &Batch{client: c}
+}
+
+// Len returns the number of calls in the batch.
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+func (b *Batch) add(decode decodeFunc, methodName string, params ...interface{}) *Batch {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	b.calls = append(b.calls, NewMethodCall(methodName, params...))
+	b.decoders = append(b.decoders, decode)
+	b.errs = append(b.errs, nil)
+	b.features = append(b.features, "")
+	return b
+}
+
+// require makes the last call require the feature.
+func (b *Batch) require(feature string) *Batch {
+	b.features[len(b.features)-1] = feature
+	return b
+}
+
+// fail adds a call which isn't sent because it's invalid.
+// Its result reports err.
+func (b *Batch) fail(err error, methodName string) *Batch {
+	b.add(decodeOK, methodName)
+	b.errs[len(b.errs)-1] = err
+	return b
+}
+
+// Do sends the calls and returns their results in the order they were added.
+// The error is only non-nil if the request as a whole failed,
+// the errors of the individual calls are reported by their result.
+//
+// Calls which require a feature the connected aria2 build lacks, like AddTorrent
+// without BitTorrent support, aren't sent and their result is a *FeatureError.
+func (b *Batch) Do() ([]BatchResult, error) {
+	return b.DoContext(context.Background())
+}
+
+// DoContext is like Do but the request is canceled when ctx is done.
+func (b *Batch) DoContext(ctx context.Context) ([]BatchResult, error) {
+	results := make([]BatchResult, len(b.calls))
+
+	var calls []*MethodCall
+	var indices []int // indices of the calls which are sent
+	for i, call := range b.calls {
+		results[i].Method = call.MethodName
+
+		if err := b.errs[i]; err != nil {
+			results[i].Err = err
+			continue
+		}
+		if feature := b.features[i]; feature != "" {
+			if err := b.client.requireFeature(ctx, feature); err != nil {
+				results[i].Err = err
+				continue
+			}
+		}
+
+		calls = append(calls, call)
+		indices = append(indices, i)
+	}
+
+	if len(calls) == 0 {
+		return results, nil
+	}
+
+	methodResults, err := b.client.MultiCallContext(ctx, calls...)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range indices {
+		if j >= len(methodResults) {
+			results[i].Err = errMissingResult
+			continue
+		}
+
+		if err := methodResults[j].Error; err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		results[i].Value, results[i].Err = b.decoders[i](b.client, methodResults[j].Result)
+	}
+
+	return results, nil
+}
+
+// decodeAs returns a decodeFunc which decodes the result into a value
+// of the same type as v.
+func decodeAs(v interface{}) decodeFunc {
+	typ := reflect.TypeOf(v)
+
+	return func(_ *Client, raw []byte) (interface{}, error) {
+		ptr := reflect.New(typ)
+		if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+			return nil, err
+		}
+		return ptr.Elem().Interface(), nil
+	}
+}
+
+func decodeGID(c *Client, raw []byte) (interface{}, error) {
+	var gid string
+	if err := json.Unmarshal(raw, &gid); err != nil {
+		return nil, err
+	}
+	return c.GetGID(gid), nil
+}
+
+func decodeGIDs(c *Client, raw []byte) (interface{}, error) {
+	var rawGIDs []string
+	if err := json.Unmarshal(raw, &rawGIDs); err != nil {
+		return nil, err
+	}
+
+	gids := make([]GID, len(rawGIDs))
+	for i, gid := range rawGIDs {
+		gids[i] = c.GetGID(gid)
+	}
+	return gids, nil
+}
+
+// decodeOK discards the "OK" returned by methods without a result.
+func decodeOK(*Client, []byte) (interface{}, error) {
+	return nil, nil
+}
+
+// AddURI adds a call of Client.AddURI, its result is a GID.
+func (b *Batch) AddURI(uris []string, options OptionSet) *Batch {
+	return b.AddURIAtPosition(uris, QueueEndPosition, options)
+}
+
+// AddURIAtPosition adds a call of Client.AddURIAtPosition, its result is a GID.
+func (b *Batch) AddURIAtPosition(uris []string, position uint, options OptionSet) *Batch {
+	return b.add(decodeGID, aria2proto.AddURI, addParams([]interface{}{uris}, position, options)...)
+}
+
+// AddTorrent adds a call of Client.AddTorrent, its result is a GID.
+func (b *Batch) AddTorrent(torrent []byte, uris []string, options OptionSet) *Batch {
+	return b.AddTorrentAtPosition(torrent, uris, QueueEndPosition, options)
+}
+
+// AddTorrentAtPosition adds a call of Client.AddTorrentAtPosition, its result is a GID.
+func (b *Batch) AddTorrentAtPosition(torrent []byte, uris []string, position uint, options OptionSet) *Batch {
+	params := []interface{}{base64.StdEncoding.EncodeToString(torrent), uris}
+	return b.add(decodeGID, aria2proto.AddTorrent, addParams(params, position, options)...).
+		require(FeatureBitTorrent)
+}
+
+// AddMetalink adds a call of Client.AddMetalink, its result is a []GID.
+func (b *Batch) AddMetalink(metalink []byte, options OptionSet) *Batch {
+	return b.AddMetalinkAtPosition(metalink, QueueEndPosition, options)
+}
+
+// AddMetalinkAtPosition adds a call of Client.AddMetalinkAtPosition, its result is a []GID.
+func (b *Batch) AddMetalinkAtPosition(metalink []byte, position uint, options OptionSet) *Batch {
+	params := []interface{}{base64.StdEncoding.EncodeToString(metalink)}
+	return b.add(decodeGIDs, aria2proto.AddMetalink, addParams(params, position, options)...).
+		require(FeatureMetalink)
+}
+
+// addParams appends the optional parameters of the methods adding a download.
+// The options are left out if they're nil, even if they're a nil pointer.
+func addParams(params []interface{}, position uint, options OptionSet) []interface{} {
+	if !isNilOptionSet(options) {
+		params = append(params, optionValues(options))
+	}
+	if position != QueueEndPosition {
+		params = append(params, position)
+	}
+	return params
+}
+
+// Remove adds a call of Client.Remove.
+func (b *Batch) Remove(gid string) *Batch {
+	return b.add(decodeOK, aria2proto.Remove, gid)
+}
+
+// ForceRemove adds a call of Client.ForceRemove.
+func (b *Batch) ForceRemove(gid string) *Batch {
+	return b.add(decodeOK, aria2proto.ForceRemove, gid)
+}
+
+// Pause adds a call of Client.Pause.
+func (b *Batch) Pause(gid string) *Batch {
+	return b.add(decodeOK, aria2proto.Pause, gid)
+}
+
+// PauseAll adds a call of Client.PauseAll.
+func (b *Batch) PauseAll() *Batch {
+	return b.add(decodeOK, aria2proto.PauseAll)
+}
+
+// ForcePause adds a call of Client.ForcePause.
+func (b *Batch) ForcePause(gid string) *Batch {
+	return b.add(decodeOK, aria2proto.ForcePause, gid)
+}
+
+// ForcePauseAll adds a call of Client.ForcePauseAll.
+func (b *Batch) ForcePauseAll() *Batch {
+	return b.add(decodeOK, aria2proto.ForcePauseAll)
+}
+
+// Unpause adds a call of Client.Unpause.
+func (b *Batch) Unpause(gid string) *Batch {
+	return b.add(decodeOK, aria2proto.Unpause, gid)
+}
+
+// UnpauseAll adds a call of Client.UnpauseAll.
+func (b *Batch) UnpauseAll() *Batch {
+	return b.add(decodeOK, aria2proto.UnpauseAll)
+}
+
+// TellStatus adds a call of Client.TellStatus, its result is a Status.
+func (b *Batch) TellStatus(gid string, keys ...string) *Batch {
+	if keys == nil {
+		keys = []string{}
+	}
+	return b.add(decodeAs(Status{}), aria2proto.TellStatus, gid, keys)
+}
+
+// GetURIs adds a call of Client.GetURIs, its result is a []URI.
+func (b *Batch) GetURIs(gid string) *Batch {
+	return b.add(decodeAs([]URI(nil)), aria2proto.GetURIs, gid)
+}
+
+// GetFiles adds a call of Client.GetFiles, its result is a []File.
+func (b *Batch) GetFiles(gid string) *Batch {
+	return b.add(decodeAs([]File(nil)), aria2proto.GetFiles, gid)
+}
+
+// GetPeers adds a call of Client.GetPeers, its result is a []Peer.
+func (b *Batch) GetPeers(gid string) *Batch {
+	return b.add(decodeAs([]Peer(nil)), aria2proto.GetPeers, gid).require(FeatureBitTorrent)
+}
+
+// GetServers adds a call of Client.GetServers, its result is a []FileServers.
+func (b *Batch) GetServers(gid string) *Batch {
+	return b.add(decodeAs([]FileServers(nil)), aria2proto.GetServers, gid)
+}
+
+// TellActive adds a call of Client.TellActive, its result is a []Status.
+func (b *Batch) TellActive(keys ...string) *Batch {
+	if keys == nil {
+		keys = []string{}
+	}
+	return b.add(decodeAs([]Status(nil)), aria2proto.TellActive, keys)
+}
+
+// TellWaiting adds a call of Client.TellWaiting, its result is a []Status.
+func (b *Batch) TellWaiting(offset int, num uint, keys ...string) *Batch {
+	if keys == nil {
+		keys = []string{}
+	}
+	return b.add(decodeAs([]Status(nil)), aria2proto.TellWaiting, offset, num, keys)
+}
+
+// TellStopped adds a call of Client.TellStopped, its result is a []Status.
+func (b *Batch) TellStopped(offset int, num uint, keys ...string) *Batch {
+	if keys == nil {
+		keys = []string{}
+	}
+	return b.add(decodeAs([]Status(nil)), aria2proto.TellStopped, offset, num, keys)
+}
+
+// ChangePosition adds a call of Client.ChangePosition, its result is an int.
+func (b *Batch) ChangePosition(gid string, pos int, how PositionSetBehaviour) *Batch {
+	params := []interface{}{gid, pos}
+	if how != "" {
+		params = append(params, how)
+	}
+	return b.add(decodeAs(0), aria2proto.ChangePosition, params...)
+}
+
+// GetOptions adds a call of Client.GetOptions, its result is an Options.
+func (b *Batch) GetOptions(gid string) *Batch {
+	return b.add(decodeAs(Options{}), aria2proto.GetOptions, gid)
+}
+
+// ChangeOptions adds a call of Client.ChangeOptions.
+func (b *Batch) ChangeOptions(gid string, options OptionSet) *Batch {
+	return b.add(decodeOK, aria2proto.ChangeOptions, gid, optionValues(options))
+}
+
+// GetGlobalOptions adds a call of Client.GetGlobalOptions, its result is a GlobalOptions.
+func (b *Batch) GetGlobalOptions() *Batch {
+	return b.add(decodeAs(GlobalOptions{}), aria2proto.GetGlobalOptions)
+}
+
+// ChangeGlobalOptions adds a call of Client.ChangeGlobalOptions.
+// If options can't be changed at runtime, the call isn't sent and
+// its result reports an *OptionError.
+func (b *Batch) ChangeGlobalOptions(options OptionSet) *Batch {
+	values := optionValues(options)
+	if err := validateRuntime(values); err != nil {
+		return b.fail(err, aria2proto.ChangeGlobalOptions)
+	}
+	return b.add(decodeOK, aria2proto.ChangeGlobalOptions, values)
+}
+
+// GetGlobalStats adds a call of Client.GetGlobalStats, its result is a Stats.
+func (b *Batch) GetGlobalStats() *Batch {
+	return b.add(decodeAs(Stats{}), aria2proto.GetGlobalStats)
+}
+
+// PurgeDownloadResults adds a call of Client.PurgeDownloadResults.
+func (b *Batch) PurgeDownloadResults() *Batch {
+	return b.add(decodeOK, aria2proto.PurgeDownloadResults)
+}
+
+// RemoveDownloadResult adds a call of Client.RemoveDownloadResult.
+func (b *Batch) RemoveDownloadResult(gid string) *Batch {
+	return b.add(decodeOK, aria2proto.RemoveDownloadResult, gid)
+}
+
+// GetVersion adds a call of Client.GetVersion, its result is a VersionInfo.
+func (b *Batch) GetVersion() *Batch {
+	return b.add(decodeAs(VersionInfo{}), aria2proto.GetVersion)
+}
+
+// GetSessionInfo adds a call of Client.GetSessionInfo, its result is a SessionInfo.
+func (b *Batch) GetSessionInfo() *Batch {
+	return b.add(decodeAs(SessionInfo{}), aria2proto.GetSessionInfo)
+}
+
+// ListMethods adds a call of Client.ListMethods, its result is a []string.
+func (b *Batch) ListMethods() *Batch {
+	return b.add(decodeAs([]string(nil)), aria2proto.ListMethods)
+}
+
+// ListNotifications adds a call of Client.ListNotifications, its result is a []string.
+func (b *Batch) ListNotifications() *Batch {
+	return b.add(decodeAs([]string(nil)), aria2proto.ListNotifications)
+}
+
+// SaveSession adds a call of Client.SaveSession.
+func (b *Batch) SaveSession() *Batch {
+	return b.add(decodeOK, aria2proto.SaveSession)
+}
@@ -0,0 +1,113 @@
+package arigo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	server, client := newTestClient(t)
+
+	existing, err := client.AddURI([]string{"https://example.org/existing"}, nil)
+	require.NoError(t, err)
+
+	results, err := client.Batch().
+		AddURI([]string{"https://example.org/file"}, &arigo.Options{Dir: "/tmp"}).
+		TellStatus(existing.GID, "gid", "status").
+		Pause("00000000000000ff").
+		GetVersion().
+		Do()
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[0].Err)
+	gid, ok := results[0].Value.(arigo.GID)
+	require.True(t, ok, "%T", results[0].Value)
+	status, ok := server.Status(gid.GID)
+	require.True(t, ok)
+	assert.Equal(t, "/tmp", status.Dir)
+
+	require.NoError(t, results[1].Err)
+	assert.Equal(t, "aria2.tellStatus", results[1].Method)
+	assert.Equal(t, arigo.Status{GID: existing.GID, Status: arigo.StatusWaiting}, results[1].Value)
+
+	assert.True(t, errors.Is(results[2].Err, arigo.ErrGIDNotFound))
+	assert.Nil(t, results[2].Value)
+
+	require.NoError(t, results[3].Err)
+	assert.IsType(t, arigo.VersionInfo{}, results[3].Value)
+}
+
+func TestBatching(t *testing.T) {
+	_, client := newTestClient(t, arigo.WithBatching(100*time.Millisecond, 0))
+
+	gids := make([]string, 3)
+	for i := range gids {
+		gid, err := client.AddURI([]string{"https://example.org/file"}, nil)
+		require.NoError(t, err)
+		gids[i] = gid.GID
+	}
+
+	type result struct {
+		gid    string
+		status arigo.Status
+		err    error
+	}
+	results := make(chan result)
+
+	lookup := append(gids, "00000000000000ff")
+	for _, gid := range lookup {
+		go func(gid string) {
+			status, err := client.TellStatus(gid, "gid")
+			results <- result{gid, status, err}
+		}(gid)
+	}
+
+	for range lookup {
+		res := <-results
+		if res.gid == "00000000000000ff" {
+			// only batched calls report a MethodCallError
+			var methodErr *arigo.MethodCallError
+			assert.True(t, errors.As(res.err, &methodErr), "%T", res.err)
+			assert.True(t, errors.Is(res.err, arigo.ErrGIDNotFound))
+			continue
+		}
+
+		assert.NoError(t, res.err)
+		assert.Equal(t, res.gid, res.status.GID)
+	}
+}
+
+func TestBatchingMaxSize(t *testing.T) {
+	// the batches are only sent once they're full
+	_, client := newTestClient(t, arigo.WithBatching(time.Hour, 2))
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.GetVersion()
+			done <- err
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("batch wasn't sent")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetVersionContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,118 @@
+package arigo
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/siku2/arigo/pkg/aria2proto"
+)
+
+// batchedCall is a call waiting to be sent by a batcher.
+type batchedCall struct {
+	call   *MethodCall
+	result chan MethodResult // receives exactly one result
+}
+
+// batcher coalesces the calls made within a window into
+// a single system.multicall request.
+type batcher struct {
+	client  *Client
+	window  time.Duration
+	maxSize int // max. number of calls per request, no limit if <= 0
+
+	mut     sync.Mutex
+	pending []*batchedCall
+	timer   *time.Timer // flushes pending once the window has passed
+}
+
+func newBatcher(client *Client, window time.Duration, maxSize int) *batcher {
+	return &batcher{
+		client:  client,
+		window:  window,
+		maxSize: maxSize,
+	}
+}
+
+// call queues the call and waits for its result.
+// If ctx is done before the result arrives, it's discarded and ctx.Err() is returned.
+func (b *batcher) call(ctx context.Context, call *MethodCall) ([]byte, error) {
+	bc := &batchedCall{call: call, result: make(chan MethodResult, 1)}
+
+	b.mut.Lock()
+	b.pending = append(b.pending, bc)
+	if b.maxSize > 0 && len(b.pending) >= b.maxSize {
+		go b.send(b.take())
+	} else if len(b.pending) == 1 {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+	b.mut.Unlock()
+
+	select {
+	case res := <-bc.result:
+		return res.Result, res.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// take removes the pending calls and stops the timer.
+// b.mut must be held.
+func (b *batcher) take() []*batchedCall {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	calls := b.pending
+	b.pending = nil
+	return calls
+}
+
+// flush sends the pending calls.
+func (b *batcher) flush() {
+	b.mut.Lock()
+	calls := b.take()
+	b.mut.Unlock()
+
+	b.send(calls)
+}
+
+// send sends the calls and delivers their results.
+// The request isn't bound to the context of any caller because it serves all of them.
+func (b *batcher) send(calls []*batchedCall) {
+	switch len(calls) {
+	case 0:
+		return
+	case 1:
+		// a multicall of a single call is a waste
+		call := calls[0].call
+		result, err := b.client.rawCall(context.Background(), call.MethodName, call.Params)
+		calls[0].result <- MethodResult{Result: result, Error: err}
+		return
+	}
+
+	methods := make([]*MethodCall, len(calls))
+	for i, bc := range calls {
+		methods[i] = bc.call
+	}
+
+	var rawResults []json.RawMessage
+	raw, err := b.client.rawCall(context.Background(), aria2proto.Multicall, b.client.multicallArgs(methods))
+	if err == nil {
+		err = json.Unmarshal(raw, &rawResults)
+	}
+
+	results := decodeMethodResults(rawResults)
+	for i, bc := range calls {
+		switch {
+		case err != nil:
+			bc.result <- MethodResult{Error: err}
+		case i < len(results):
+			bc.result <- results[i]
+		default:
+			bc.result <- MethodResult{Error: errMissingResult}
+		}
+	}
+}
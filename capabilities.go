@@ -0,0 +1,234 @@
+package arigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/siku2/arigo/pkg/aria2proto"
+)
+
+// Features aria2 can be built with, as listed in VersionInfo.EnabledFeatures.
+const (
+	FeatureAsyncDNS       = "Async DNS"
+	FeatureBitTorrent     = "BitTorrent"
+	FeatureFirefox3Cookie = "Firefox3 Cookie"
+	FeatureGZip           = "GZip"
+	FeatureHTTPS          = "HTTPS"
+	FeatureMessageDigest  = "Message Digest"
+	FeatureMetalink       = "Metalink"
+	FeatureSFTP           = "SFTP"
+	FeatureXMLRPC         = "XML-RPC"
+)
+
+// capabilitiesRetryDelay is the time requireFeature waits before requesting
+// the capabilities again after the request failed.
+const capabilitiesRetryDelay = time.Minute
+
+// ErrFeatureNotSupported is matched by the errors of calls which require a feature
+// the connected aria2 build lacks.
+var ErrFeatureNotSupported = errors.New("feature not supported")
+
+// FeatureError is the error of a call which requires a feature
+// the connected aria2 build lacks.
+// It matches ErrFeatureNotSupported using errors.Is.
+type FeatureError struct {
+	Feature string // The missing feature, for example FeatureBitTorrent
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("aria2 was built without %s support", e.Feature)
+}
+
+// Is reports whether target is ErrFeatureNotSupported.
+func (e *FeatureError) Is(target error) bool {
+	return target == ErrFeatureNotSupported
+}
+
+// Capabilities describes what the connected aria2 build supports.
+type Capabilities struct {
+	Version       string   // Version number of aria2
+	Features      []string // Enabled features, see the Feature constants
+	Methods       []string // Names of the available methods, nil if aria2 doesn't tell
+	Notifications []string // Names of the notifications aria2 sends, nil if aria2 doesn't tell
+
+	AsyncDNS       bool
+	BitTorrent     bool
+	Firefox3Cookie bool
+	GZip           bool
+	HTTPS          bool
+	MessageDigest  bool
+	Metalink       bool
+	SFTP           bool
+	XMLRPC         bool
+}
+
+func newCapabilities(version VersionInfo, methods, notifications []string) Capabilities {
+	caps := Capabilities{
+		Version:       version.Version,
+		Features:      version.EnabledFeatures,
+		Methods:       methods,
+		Notifications: notifications,
+	}
+
+	for _, feature := range version.EnabledFeatures {
+		switch feature {
+		case FeatureAsyncDNS:
+			caps.AsyncDNS = true
+		case FeatureBitTorrent:
+			caps.BitTorrent = true
+		case FeatureFirefox3Cookie:
+			caps.Firefox3Cookie = true
+		case FeatureGZip:
+			caps.GZip = true
+		case FeatureHTTPS:
+			caps.HTTPS = true
+		case FeatureMessageDigest:
+			caps.MessageDigest = true
+		case FeatureMetalink:
+			caps.Metalink = true
+		case FeatureSFTP:
+			caps.SFTP = true
+		case FeatureXMLRPC:
+			caps.XMLRPC = true
+		}
+	}
+
+	return caps
+}
+
+// HasFeature reports whether the feature is enabled.
+func (caps *Capabilities) HasFeature(feature string) bool {
+	return contains(caps.Features, feature)
+}
+
+// HasMethod reports whether the method is available.
+// If aria2 doesn't list its methods, all methods are assumed to be available.
+func (caps *Capabilities) HasMethod(method string) bool {
+	return caps.Methods == nil || contains(caps.Methods, method)
+}
+
+// HasNotification reports whether aria2 sends the notification.
+// If aria2 doesn't list its notifications, all of them are assumed to be sent.
+func (caps *Capabilities) HasNotification(notification string) bool {
+	return caps.Notifications == nil || contains(caps.Notifications, notification)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ListMethods returns the names of all methods aria2 supports.
+func (c *Client) ListMethods() ([]string, error) {
+	return c.ListMethodsContext(context.Background())
+}
+
+// ListMethodsContext is like ListMethods but the call is canceled when ctx is done.
+func (c *Client) ListMethodsContext(ctx context.Context) ([]string, error) {
+	// system methods don't take the secret token
+	var reply []string
+	err := c.callContext(ctx, aria2proto.ListMethods, []interface{}{}, &reply)
+
+	return reply, err
+}
+
+// ListNotifications returns the names of all notifications aria2 sends.
+func (c *Client) ListNotifications() ([]string, error) {
+	return c.ListNotificationsContext(context.Background())
+}
+
+// ListNotificationsContext is like ListNotifications but the call is canceled when ctx is done.
+func (c *Client) ListNotificationsContext(ctx context.Context) ([]string, error) {
+	var reply []string
+	err := c.callContext(ctx, aria2proto.ListNotifications, []interface{}{}, &reply)
+
+	return reply, err
+}
+
+// Capabilities returns what the connected aria2 build supports.
+// The version, methods and notifications are requested in a single multicall.
+//
+// The capabilities are remembered to check whether the features required by
+// methods like AddTorrent are available. Clients created by DialReconnecting
+// request them again after reconnecting because aria2 might have been replaced.
+func (c *Client) Capabilities() (Capabilities, error) {
+	return c.CapabilitiesContext(context.Background())
+}
+
+// CapabilitiesContext is like Capabilities but the call is canceled when ctx is done.
+func (c *Client) CapabilitiesContext(ctx context.Context) (Capabilities, error) {
+	results, err := c.Batch().GetVersion().ListMethods().ListNotifications().DoContext(ctx)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	if err := results[0].Err; err != nil {
+		return Capabilities{}, err
+	}
+
+	// the lists aren't available in old versions of aria2
+	var lists [2][]string
+	for i, res := range results[1:] {
+		if res.Err != nil && !errors.Is(res.Err, ErrMethodNotFound) {
+			return Capabilities{}, res.Err
+		}
+		lists[i], _ = res.Value.([]string)
+	}
+
+	caps := newCapabilities(results[0].Value.(VersionInfo), lists[0], lists[1])
+
+	c.capsMut.Lock()
+	c.caps = &caps
+	c.capsFailed = time.Time{}
+	c.capsMut.Unlock()
+
+	return caps, nil
+}
+
+// requireFeature returns a *FeatureError if the connected aria2 build lacks the feature.
+// If the capabilities can't be determined, the feature is assumed to be available
+// and the call requiring it is left to fail on its own.
+// The request isn't repeated for capabilitiesRetryDelay after it failed.
+func (c *Client) requireFeature(ctx context.Context, feature string) error {
+	c.capsMut.Lock()
+	caps, failed := c.caps, c.capsFailed
+	c.capsMut.Unlock()
+
+	if caps == nil {
+		if !failed.IsZero() && time.Since(failed) < capabilitiesRetryDelay {
+			return nil
+		}
+
+		fetched, err := c.CapabilitiesContext(ctx)
+		if err != nil {
+			// a canceled call says nothing about aria2
+			if ctx.Err() == nil {
+				c.capsMut.Lock()
+				c.capsFailed = time.Now()
+				c.capsMut.Unlock()
+			}
+			return nil
+		}
+		caps = &fetched
+	}
+
+	if !caps.HasFeature(feature) {
+		return &FeatureError{Feature: feature}
+	}
+	return nil
+}
+
+// resetCapabilities forgets the capabilities, they're requested again when needed.
+func (c *Client) resetCapabilities() {
+	c.capsMut.Lock()
+	defer c.capsMut.Unlock()
+
+	c.caps = nil
+	c.capsFailed = time.Time{}
+}
@@ -0,0 +1,97 @@
+package arigo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	_, client := newTestClient(t)
+
+	methods, err := client.ListMethods()
+	require.NoError(t, err)
+	assert.Contains(t, methods, "aria2.addUri")
+
+	notifications, err := client.ListNotifications()
+	require.NoError(t, err)
+	assert.Contains(t, notifications, "aria2.onDownloadStart")
+
+	caps, err := client.Capabilities()
+	require.NoError(t, err)
+	assert.Equal(t, "1.36.0", caps.Version)
+	assert.True(t, caps.BitTorrent)
+	assert.True(t, caps.AsyncDNS)
+	assert.False(t, caps.SFTP)
+	assert.True(t, caps.HasFeature(arigo.FeatureMetalink))
+	assert.True(t, caps.HasMethod("aria2.addTorrent"))
+	assert.False(t, caps.HasMethod("aria2.unknown"))
+	assert.True(t, caps.HasNotification("aria2.onBtDownloadComplete"))
+}
+
+func TestRequireFeature(t *testing.T) {
+	server, client := newTestClient(t)
+	server.SetVersion(arigo.VersionInfo{Version: "1.36.0", EnabledFeatures: []string{"Async DNS"}})
+
+	_, err := client.AddTorrent([]byte("d4:infod4:name4:testee"), nil, nil)
+	var featureErr *arigo.FeatureError
+	require.True(t, errors.As(err, &featureErr), "%v", err)
+	assert.Equal(t, arigo.FeatureBitTorrent, featureErr.Feature)
+	assert.True(t, errors.Is(err, arigo.ErrFeatureNotSupported))
+	assert.EqualError(t, err, "aria2 was built without BitTorrent support")
+
+	_, err = client.AddMetalink([]byte("<metalink/>"), nil)
+	assert.True(t, errors.Is(err, arigo.ErrFeatureNotSupported))
+
+	assert.Empty(t, server.Downloads())
+
+	// calls of a batch are checked as well, the others are still sent
+	results, err := client.Batch().
+		AddTorrent([]byte("d4:infod4:name4:testee"), nil, nil).
+		AddMetalink([]byte("<metalink/>"), nil).
+		AddURI([]string{"https://example.org/file"}, nil).
+		Do()
+	require.NoError(t, err)
+	assert.True(t, errors.Is(results[0].Err, arigo.ErrFeatureNotSupported))
+	assert.True(t, errors.Is(results[1].Err, arigo.ErrFeatureNotSupported))
+	assert.NoError(t, results[2].Err)
+	assert.Len(t, server.Downloads(), 1)
+}
+
+func TestRequireFeatureFetchFails(t *testing.T) {
+	var multicalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		res := map[string]interface{}{"id": req.ID, "result": "2089b05ecca3d829"}
+		if req.Method == "system.multicall" {
+			atomic.AddInt32(&multicalls, 1)
+			res = map[string]interface{}{"id": req.ID, "error": map[string]interface{}{"code": 1, "message": "Internal error"}}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	client, err := arigo.DialHTTP(server.URL+"/jsonrpc", "")
+	require.NoError(t, err)
+	defer client.Close()
+
+	// the feature is assumed to be available, and the failure is remembered
+	for i := 0; i < 3; i++ {
+		gid, err := client.AddTorrent([]byte("d4:infod4:name4:testee"), nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "2089b05ecca3d829", gid.GID)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&multicalls))
+}
@@ -0,0 +1,241 @@
+package arigo
+
+import (
+	"context"
+	"time"
+)
+
+// chainEvents are the events which can finish a download of a chain.
+var chainEvents = []EventType{CompleteEvent, BTCompleteEvent, ErrorEvent, StopEvent}
+
+// downloadChain keeps track of a download and the downloads following it.
+type downloadChain struct {
+	order    []string          // GIDs in the order they were discovered
+	statuses map[string]Status // final statuses of the finished downloads
+	pending  map[string]bool   // GIDs of the downloads which haven't finished yet
+}
+
+// track adds the download to the chain.
+// It returns false if it's already part of it.
+func (ch *downloadChain) track(gid string) bool {
+	if _, ok := ch.statuses[gid]; ok || ch.pending[gid] {
+		return false
+	}
+
+	ch.pending[gid] = true
+	ch.order = append(ch.order, gid)
+	return true
+}
+
+// results returns the final statuses in the order the downloads were discovered in.
+// The error is the one of the first download which didn't complete.
+func (ch *downloadChain) results() ([]Status, error) {
+	statuses := make([]Status, 0, len(ch.statuses))
+	var err error
+
+	for _, gid := range ch.order {
+		status, ok := ch.statuses[gid]
+		if !ok {
+			continue
+		}
+
+		statuses = append(statuses, status)
+		if err == nil {
+			err = statusError(status)
+		}
+	}
+
+	return statuses, err
+}
+
+// chainFinished reports whether a download of a chain has finished.
+// BitTorrent downloads which are seeding have finished downloading,
+// so they don't have to be waited for.
+func chainFinished(status Status) bool {
+	switch status.Status {
+	case StatusCompleted, StatusError, StatusRemoved:
+		return true
+	case StatusActive:
+		return status.Seeder
+	}
+	return false
+}
+
+// statusError returns the error WaitForDownload would return for the finished download.
+func statusError(status Status) error {
+	switch status.Status {
+	case StatusError:
+		return newDownloadError(status)
+	case StatusRemoved:
+		return ErrDownloadStopped
+	}
+	return nil
+}
+
+// WaitForDownloadChain waits for the download denoted by gid and all downloads
+// following it to finish.
+//
+// When aria2 downloads a magnet link, a .torrent or a Metalink file, the download
+// completes once the metadata is available and new downloads for the actual content
+// are generated. These are listed in the FollowedBy field of the status and
+// link back to their parent using Following.
+// WaitForDownloadChain walks these links, so it only returns once the content
+// was downloaded.
+// BitTorrent downloads are considered finished once they're complete and start seeding.
+//
+// It returns the final statuses of all downloads in the chain, starting with
+// the one denoted by gid. If any of them failed or was removed, a *DownloadError
+// or ErrDownloadStopped is returned respectively, along with the statuses.
+func (c *Client) WaitForDownloadChain(gid string) ([]Status, error) {
+	return c.WaitForDownloadChainContext(context.Background(), gid)
+}
+
+// WaitForDownloadChainContext is like WaitForDownloadChain but stops waiting
+// when ctx is done, in which case ctx.Err() is returned
+// together with the statuses of the downloads which have finished so far.
+func (c *Client) WaitForDownloadChainContext(ctx context.Context, gid string) ([]Status, error) {
+	chain, err := c.waitForChain(ctx, gid)
+	if err != nil {
+		statuses, _ := chain.results()
+		return statuses, err
+	}
+
+	return chain.results()
+}
+
+// waitForChain waits for the download and all downloads following it to finish.
+// The returned chain is never nil.
+func (c *Client) waitForChain(ctx context.Context, gid string) (*downloadChain, error) {
+	chain := &downloadChain{
+		statuses: make(map[string]Status),
+		pending:  make(map[string]bool),
+	}
+
+	var events <-chan Event
+	var stream *EventStream
+	var ticks <-chan time.Time
+
+	if c.pollInterval > 0 {
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	} else {
+		// subscribe before the first status check so no event is missed
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream = c.Events(streamCtx, EventFilter{Types: chainEvents})
+		events = stream.C
+	}
+
+	defer func() {
+		for gid := range chain.pending {
+			c.waiters.remove(gid)
+		}
+	}()
+
+	// check records the download if it has finished and starts
+	// tracking the downloads following it.
+	var check func(gid string) error
+	check = func(gid string) error {
+		status, err := c.TellStatusContext(ctx, gid)
+		if err != nil {
+			return err
+		}
+
+		if !chainFinished(status) {
+			return nil
+		}
+
+		delete(chain.pending, gid)
+		c.waiters.remove(gid)
+		chain.statuses[gid] = status
+
+		for _, follower := range status.FollowedBy {
+			if chain.track(follower) {
+				// register as a waiter so the download is checked after reconnecting
+				c.waiters.add(follower)
+
+				if err := check(follower); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	chain.track(gid)
+	c.waiters.add(gid)
+
+	if err := check(gid); err != nil {
+		return chain, err
+	}
+
+	for len(chain.pending) > 0 {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				if err := ctx.Err(); err != nil {
+					return chain, err
+				}
+				return chain, stream.Err()
+			}
+
+			if chain.pending[evt.GID] {
+				if err := check(evt.GID); err != nil {
+					return chain, err
+				}
+			}
+		case <-ticks:
+			pending := make([]string, 0, len(chain.pending))
+			for gid := range chain.pending {
+				pending = append(pending, gid)
+			}
+
+			for _, gid := range pending {
+				if err := check(gid); err != nil {
+					return chain, err
+				}
+			}
+		case <-ctx.Done():
+			return chain, ctx.Err()
+		}
+	}
+
+	return chain, nil
+}
+
+// DownloadChain adds a new download and waits for it and all downloads
+// following it to finish, see WaitForDownloadChain.
+// It returns the final statuses of all downloads in the chain.
+func (c *Client) DownloadChain(uris []string, options OptionSet) ([]Status, error) {
+	return c.DownloadChainWithContext(context.Background(), uris, options)
+}
+
+// DownloadChainWithContext is like DownloadChain but the passed context
+// can be used to cancel the downloads.
+// If ctx is done, all downloads of the chain which haven't finished yet are deleted.
+func (c *Client) DownloadChainWithContext(ctx context.Context, uris []string, options OptionSet) ([]Status, error) {
+	gid, err := c.AddURIContext(ctx, uris, options)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := c.waitForChain(ctx, gid.GID)
+	if ctx.Err() != nil {
+		for pending := range chain.pending {
+			_ = c.Delete(pending)
+		}
+
+		statuses, _ := chain.results()
+		return statuses, ctx.Err()
+	}
+
+	if err != nil {
+		statuses, _ := chain.results()
+		return statuses, err
+	}
+
+	return chain.results()
+}
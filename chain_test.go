@@ -0,0 +1,88 @@
+package arigo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/siku2/arigo"
+	"github.com/siku2/arigo/arigotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadChain(t *testing.T) {
+	server, client := newTestClient(t)
+
+	// a magnet link whose metadata download is followed by the content,
+	// which keeps seeding once it's complete
+	server.SetDefaultSimulation(&arigotest.Simulation{
+		TotalLength: 100,
+		Speed:       100,
+		FollowedBy: []arigotest.Simulation{
+			{TotalLength: 1000, Speed: 100, BitTorrent: true, SeedTime: time.Hour},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type result struct {
+		statuses []arigo.Status
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		statuses, err := client.DownloadChainWithContext(ctx, []string{"magnet:?xt=urn:btih:248d0a1cd08284299de78d5c1ed359bb46717d8c"}, nil)
+		done <- result{statuses, err}
+	}()
+
+	for i := 0; ; i++ {
+		select {
+		case res := <-done:
+			require.NoError(t, res.err)
+			require.Len(t, res.statuses, 2)
+
+			metadata, content := res.statuses[0], res.statuses[1]
+			assert.Equal(t, arigo.StatusCompleted, metadata.Status)
+			assert.Equal(t, []string{content.GID}, metadata.FollowedBy)
+			assert.Equal(t, metadata.GID, content.Following)
+			assert.Equal(t, uint(1000), content.CompletedLength)
+			assert.Equal(t, arigo.StatusActive, content.Status)
+			assert.True(t, content.Seeder)
+
+			// the metadata alone takes one second, the content ten more
+			assert.True(t, i > 10, "returned after %d seconds", i)
+
+			// the content download itself only completes once it's done seeding
+			waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer waitCancel()
+			assert.Equal(t, context.DeadlineExceeded, client.WaitForDownloadContext(waitCtx, content.GID))
+			return
+		case <-time.After(10 * time.Millisecond):
+			server.Advance(time.Second)
+		}
+	}
+}
+
+func TestWaitForDownloadChainError(t *testing.T) {
+	server, client := newTestClient(t)
+
+	gid, err := client.AddURI([]string{"https://example.org/file.torrent"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, server.Simulate(gid.GID, arigotest.Simulation{
+		TotalLength: 10,
+		Speed:       10,
+		FollowedBy:  []arigotest.Simulation{{TotalLength: 10, ExitStatus: arigo.NetworkError}},
+	}))
+
+	server.Advance(time.Second)
+	server.Advance(time.Second)
+
+	statuses, err := gid.WaitForDownloadChain()
+	assert.True(t, errors.Is(err, arigo.ErrDownloadError))
+	require.Len(t, statuses, 2)
+	assert.Equal(t, arigo.StatusCompleted, statuses[0].Status)
+	assert.Equal(t, arigo.StatusError, statuses[1].Status)
+}
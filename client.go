@@ -7,24 +7,41 @@ import (
 	"errors"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/cenkalti/rpc2"
-	"github.com/cenkalti/rpc2/jsonrpc"
-	"github.com/gorilla/websocket"
+	"github.com/siku2/arigo/internal/pkg/callctx"
+	"github.com/siku2/arigo/internal/pkg/httprpc"
+	"github.com/siku2/arigo/internal/pkg/jsonrpc"
 	"github.com/siku2/arigo/internal/pkg/wsrpc"
+	"github.com/siku2/arigo/internal/pkg/xmlrpc"
 	"github.com/siku2/arigo/pkg/aria2proto"
 )
 
 const (
 	// QueueEndPosition represents the end of the download queue.
 	QueueEndPosition = ^uint(0)
+
+	// DefaultPollInterval is the interval at which clients without
+	// events poll the status of a download they're waiting for.
+	DefaultPollInterval = time.Second
 )
 
 var (
-	// ErrDownloadError represents an error that occurred during the download
+	// ErrDownloadError represents an error that occurred during the download.
+	// The errors of failed downloads are *DownloadError values which match it using errors.Is.
 	ErrDownloadError = errors.New("download encountered error")
 	// ErrDownloadStopped is the error returned when a download is stopped
 	ErrDownloadStopped = errors.New("download stopped")
+	// ErrClientClosed is reported by Client.Err after the client was closed
+	ErrClientClosed = errors.New("client closed")
+	// ErrConnectionLost is reported by Client.Err when the connection was lost
+	// but the transport didn't report a reason.
+	ErrConnectionLost = errors.New("connection lost")
+	// ErrKeepAliveTimeout is reported by Client.Err when aria2 didn't answer the
+	// keepalive pings in time. See WithKeepAlive.
+	ErrKeepAliveTimeout = wsrpc.ErrKeepAliveTimeout
 )
 
 // URIs creates a string slice from the given uris.
@@ -34,51 +51,115 @@ func URIs(uris ...string) []string {
 	return uris
 }
 
-// Client represents a connection to an aria2 rpc interface over websocket.
+// Client represents a connection to an aria2 rpc interface.
 type Client struct {
 	rpcClient *rpc2.Client
 	closed    bool
+	closing   chan struct{}
+	mut       sync.RWMutex // protects rpcClient, transportErr, closed and reconnect.err
+
+	// transportErr returns why the connection of rpcClient was lost.
+	// It's nil if the transport can't tell.
+	transportErr func() error
+
+	done     chan struct{}
+	doneErr  error
+	doneOnce sync.Once
 
 	authToken string
 
 	evtTarget eventTarget
+	events    *eventBus
+
+	reconnect *reconnector
+	waiters   waiterSet
+
+	// batcher coalesces concurrent calls, it's nil if batching is disabled.
+	batcher *batcher
+
+	capsMut    sync.Mutex
+	caps       *Capabilities // nil until the capabilities are requested
+	capsFailed time.Time     // when requesting the capabilities failed, zero if it didn't
+
+	// contextCalls is set if the codecs created by the client understand
+	// *callctx.Params, which lets them clean up abandoned calls.
+	contextCalls bool
+
+	// pollInterval is used to poll the status of downloads instead of
+	// waiting for events. It's zero if the transport supports events.
+	pollInterval time.Duration
 }
 
 // NewClient creates a new client.
 // The client needs to be manually ran
 // using the Run method.
 func NewClient(rpcClient *rpc2.Client, authToken string) *Client {
+	return newClient(rpcClient, authToken, newEventBus(false))
+}
+
+// newClient creates a new client which delivers event streams using events.
+func newClient(rpcClient *rpc2.Client, authToken string, events *eventBus) *Client {
 	client := &Client{
 		rpcClient: rpcClient,
 		authToken: authToken,
 		closed:    false,
+		closing:   make(chan struct{}),
+		done:      make(chan struct{}),
+		events:    events,
 	}
+	events.target = &client.evtTarget
 
-	rpcClient.Handle(aria2proto.OnDownloadStart, client.onDownloadStart)
-	rpcClient.Handle(aria2proto.OnDownloadPause, client.onDownloadPause)
-	rpcClient.Handle(aria2proto.OnDownloadStop, client.onDownloadStop)
-	rpcClient.Handle(aria2proto.OnDownloadComplete, client.onDownloadComplete)
-	rpcClient.Handle(aria2proto.OnDownloadError, client.onDownloadError)
-	rpcClient.Handle(aria2proto.OnBTDownloadComplete, client.onBTDownloadComplete)
+	client.registerHandlers(rpcClient)
 
 	return client
 }
 
+// registerHandlers registers the handlers for the aria2 notifications on rpcClient.
+func (c *Client) registerHandlers(rpcClient *rpc2.Client) {
+	rpcClient.Handle(aria2proto.OnDownloadStart, c.onDownloadStart)
+	rpcClient.Handle(aria2proto.OnDownloadPause, c.onDownloadPause)
+	rpcClient.Handle(aria2proto.OnDownloadStop, c.onDownloadStop)
+	rpcClient.Handle(aria2proto.OnDownloadComplete, c.onDownloadComplete)
+	rpcClient.Handle(aria2proto.OnDownloadError, c.onDownloadError)
+	rpcClient.Handle(aria2proto.OnBTDownloadComplete, c.onBTDownloadComplete)
+}
+
+// dialWebSocket establishes a WebSocket connection to url and
+// creates a rpc2 client using it.
+// The notifications received by the client are published to events.
+// The returned function reports why the connection was lost.
+func dialWebSocket(ctx context.Context, url string, opts dialOptions, events *eventBus) (*rpc2.Client, func() error, error) {
+	ws, _, err := opts.dialer.DialContext(ctx, url, opts.header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rwc := wsrpc.NewReadWriteCloser(ws)
+	if opts.keepAliveInterval > 0 {
+		rwc.KeepAlive(opts.keepAliveInterval, opts.keepAliveTimeout)
+	}
+
+	codec := newNotificationCodec(jsonrpc.NewCodec(&rwc), events)
+	return rpc2.NewClientWithCodec(codec), rwc.Err, nil
+}
+
 // DialContext creates a new connection to an aria2 rpc interface.
+// The connection can be configured using opts.
 // It returns a new client.
-func DialContext(ctx context.Context, url string, authToken string) (client *Client, err error) {
-	dialer := websocket.Dialer{}
+func DialContext(ctx context.Context, url string, authToken string, opts ...DialOption) (client *Client, err error) {
+	events := newEventBus(true)
+	dialOpts := newDialOptions(opts)
 
-	ws, _, err := dialer.DialContext(ctx, url, http.Header{})
+	rpcClient, transportErr, err := dialWebSocket(ctx, url, dialOpts, events)
 	if err != nil {
+		events.close(err)
 		return
 	}
 
-	rwc := wsrpc.NewReadWriteCloser(ws)
-	codec := jsonrpc.NewJSONCodec(&rwc)
-	rpcClient := rpc2.NewClientWithCodec(codec)
-
-	client = NewClient(rpcClient, authToken)
+	client = newClient(rpcClient, authToken, events)
+	client.transportErr = transportErr
+	client.contextCalls = true
+	dialOpts.apply(client)
 	go client.Run()
 
 	return
@@ -86,64 +167,284 @@ func DialContext(ctx context.Context, url string, authToken string) (client *Cli
 
 // Dial creates a new connection to an aria2 rpc interface.
 // It returns a new client.
-func Dial(url string, authToken string) (client *Client, err error) {
-	return DialContext(context.Background(), url, authToken)
+func Dial(url string, authToken string, opts ...DialOption) (client *Client, err error) {
+	return DialContext(context.Background(), url, authToken, opts...)
+}
+
+// DialHTTP creates a new client for the aria2 JSON-RPC interface over plain HTTP.
+// url is the address of the endpoint, usually "http://localhost:6800/jsonrpc".
+// Every call is sent as a separate POST request.
+//
+// aria2 can't send notifications over HTTP, so the returned client never
+// receives any events. Methods waiting for a download to finish poll its
+// status every DefaultPollInterval instead, use WithPollInterval to change it.
+// The header, basic authentication, TLS and proxy options apply to every
+// request, options which only apply to WebSocket connections are ignored.
+func DialHTTP(url string, authToken string, opts ...DialOption) (*Client, error) {
+	return DialHTTPWithClient(url, authToken, http.DefaultClient, opts...)
+}
+
+// DialHTTPWithClient is like DialHTTP but uses httpClient to perform the requests.
+// WithTLSConfig and WithProxy are applied to a copy of its transport,
+// they return ErrTransportNotConfigurable if it isn't an *http.Transport.
+func DialHTTPWithClient(url string, authToken string, httpClient *http.Client, opts ...DialOption) (*Client, error) {
+	// make sure the url is valid before the first call is made
+	if _, err := http.NewRequest(http.MethodPost, url, nil); err != nil {
+		return nil, err
+	}
+
+	dialOpts := newDialOptions(opts)
+	httpClient, err := dialOpts.httpClient(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	rwc := httprpc.NewReadWriteCloser(url, httpClient, dialOpts.header)
+	codec := jsonrpc.NewCodec(rwc)
+	rpcClient := rpc2.NewClientWithCodec(codec)
+
+	client := NewClient(rpcClient, authToken)
+	client.contextCalls = true
+	client.pollInterval = DefaultPollInterval
+	dialOpts.apply(client)
+	go client.Run()
+
+	return client, nil
+}
+
+// DialXMLRPC creates a new client for the aria2 XML-RPC interface.
+// url is the address of the endpoint, usually "http://localhost:6800/rpc".
+//
+// Like the plain HTTP transport, XML-RPC doesn't support events and
+// the status of downloads is polled instead.
+func DialXMLRPC(url string, authToken string, opts ...DialOption) (*Client, error) {
+	return DialXMLRPCWithClient(url, authToken, http.DefaultClient, opts...)
+}
+
+// DialXMLRPCWithClient is like DialXMLRPC but uses httpClient to perform the requests.
+// The options are applied like they are by DialHTTPWithClient.
+func DialXMLRPCWithClient(url string, authToken string, httpClient *http.Client, opts ...DialOption) (*Client, error) {
+	if _, err := http.NewRequest(http.MethodPost, url, nil); err != nil {
+		return nil, err
+	}
+
+	dialOpts := newDialOptions(opts)
+	httpClient, err := dialOpts.httpClient(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := xmlrpc.NewCodec(url, httpClient, dialOpts.header)
+	rpcClient := rpc2.NewClientWithCodec(codec)
+
+	client := NewClient(rpcClient, authToken)
+	client.contextCalls = true
+	client.pollInterval = DefaultPollInterval
+	dialOpts.apply(client)
+	go client.Run()
+
+	return client, nil
 }
 
 // Run runs the underlying rpcClient.
 // There's no need to call this if the client
 // was created using the Dial function.
+//
+// Run returns when the connection is lost, after which the client is done.
 func (c *Client) Run() {
-	c.rpcClient.Run()
+	c.finish(c.runRPC())
+}
+
+// runRPC runs the current rpc client and returns why its connection was lost.
+func (c *Client) runRPC() error {
+	c.rpc().Run()
+
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.closed {
+		return ErrClientClosed
+	}
+	if c.transportErr != nil {
+		if err := c.transportErr(); err != nil {
+			return err
+		}
+	}
+	return ErrConnectionLost
+}
+
+// finish marks the client as done.
+// Only the first call has an effect.
+func (c *Client) finish(err error) {
+	c.doneOnce.Do(func() {
+		c.doneErr = err
+		close(c.done)
+
+		c.events.close(err)
+
+		if c.batcher != nil {
+			// fail the pending calls right away instead of after the window
+			go c.batcher.flush()
+		}
+	})
+}
+
+// Done returns a channel which is closed when the client stops working,
+// either because it was closed or because the connection to aria2 was lost.
+// The reason is reported by Err.
+//
+// Clients created by DialReconnecting keep working when the connection is lost
+// and are only done once they're closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns nil if Done is not yet closed.
+// Otherwise it returns the reason the client stopped working:
+// ErrClientClosed if the client was closed, ErrKeepAliveTimeout if aria2 didn't
+// answer the keepalive pings or the error reported by the transport.
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.doneErr
+	default:
+		return nil
+	}
 }
 
 // Close closes the connection to the aria2 rpc interface.
 // The client becomes unusable after that point.
 func (c *Client) Close() error {
-	c.closed = true
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.closing)
+	}
+	c.finish(ErrClientClosed)
 
 	return c.rpcClient.Close()
 }
 
+// rpc returns the current rpc client.
+func (c *Client) rpc() *rpc2.Client {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	return c.rpcClient
+}
+
+// callContext calls the method using the current rpc client.
+// If batching is enabled, the call is sent as part of a multicall.
+// If ctx is done before the call completes, the reply is discarded and ctx.Err() is returned.
+// Clients created by the Dial functions also cancel the request and stop waiting for its response.
+func (c *Client) callContext(ctx context.Context, method string, args interface{}, reply interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var rawReply []byte
+	var err error
+
+	if params, ok := args.([]interface{}); ok && c.batcher != nil && method != aria2proto.Multicall {
+		rawReply, err = c.batcher.call(ctx, NewMethodCall(method, params...))
+	} else {
+		rawReply, err = c.rawCall(ctx, method, args)
+	}
+
+	if err != nil || reply == nil {
+		return err
+	}
+
+	return json.Unmarshal(rawReply, reply)
+}
+
+// rawCall calls the method using the current rpc client and returns the raw reply.
+func (c *Client) rawCall(ctx context.Context, method string, args interface{}) ([]byte, error) {
+	// The reply is only decoded once the call has completed so that
+	// an abandoned call can't write to it anymore.
+	var rawReply json.RawMessage
+	if c.contextCalls {
+		args = &callctx.Params{Ctx: ctx, Params: args}
+	}
+	call := c.rpc().Go(method, args, &rawReply, make(chan *rpc2.Call, 1))
+
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if call.Error != nil {
+		return nil, callError(call.Error)
+	}
+
+	return rawReply, nil
+}
+
 func (c *Client) onDownloadStart(_ *rpc2.Client, event *DownloadEvent, _ *interface{}) error {
-	c.evtTarget.Dispatch(StartEvent, event)
+	c.handleEvent(StartEvent, event)
 	return nil
 }
 func (c *Client) onDownloadPause(_ *rpc2.Client, event *DownloadEvent, _ *interface{}) error {
-	c.evtTarget.Dispatch(PauseEvent, event)
+	c.handleEvent(PauseEvent, event)
 	return nil
 }
 func (c *Client) onDownloadStop(_ *rpc2.Client, event *DownloadEvent, _ *interface{}) error {
-	c.evtTarget.Dispatch(StopEvent, event)
+	c.handleEvent(StopEvent, event)
 	return nil
 }
 func (c *Client) onDownloadComplete(_ *rpc2.Client, event *DownloadEvent, _ *interface{}) error {
-	c.evtTarget.Dispatch(CompleteEvent, event)
+	c.handleEvent(CompleteEvent, event)
 	return nil
 }
 func (c *Client) onDownloadError(_ *rpc2.Client, event *DownloadEvent, _ *interface{}) error {
-	c.evtTarget.Dispatch(ErrorEvent, event)
+	c.handleEvent(ErrorEvent, event)
 	return nil
 }
 func (c *Client) onBTDownloadComplete(_ *rpc2.Client, event *DownloadEvent, _ *interface{}) error {
-	c.evtTarget.Dispatch(BTCompleteEvent, event)
+	c.handleEvent(BTCompleteEvent, event)
 	return nil
 }
 
 // Subscribe registers the given listener for an event.
 // The listener will be called every time the event occurs.
+//
+// Listeners are called on their own goroutine, one event at a time and in the
+// order the events were received in. A slow listener only delays itself.
+// It's safe to subscribe and unsubscribe from inside a listener.
 func (c *Client) Subscribe(evtType EventType, listener EventListener) UnsubscribeFunc {
 	return c.evtTarget.Subscribe(evtType, listener)
 }
 
 // WaitForDownload waits for a download denoted by its gid to finish.
 func (c *Client) WaitForDownload(gid string) error {
+	return c.WaitForDownloadContext(context.Background(), gid)
+}
+
+// WaitForDownloadContext waits for a download denoted by its gid to finish
+// or for ctx to be done, in which case ctx.Err() is returned.
+func (c *Client) WaitForDownloadContext(ctx context.Context, gid string) error {
+	return c.waitForDownload(ctx, gid)
+}
+
+// waitForDownload waits for the download to finish or for ctx to be done,
+// in which case ctx.Err() is returned.
+func (c *Client) waitForDownload(ctx context.Context, gid string) error {
+	if c.pollInterval > 0 {
+		return c.pollForDownload(ctx, gid)
+	}
+
 	channel := make(chan error, 1)
 
 	sendResponse := func(err error) EventListener {
 		return func(ev *DownloadEvent) {
 			if ev.GID == gid {
-				channel <- err
+				select {
+				case channel <- err:
+				default:
+				}
 			}
 		}
 	}
@@ -152,59 +453,92 @@ func (c *Client) WaitForDownload(gid string) error {
 	completeUnsub := c.Subscribe(CompleteEvent, sendResponse(nil))
 	errUnsub := c.Subscribe(ErrorEvent, sendResponse(ErrDownloadError))
 
-	err := <-channel
+	// register as a waiter so the download is checked after reconnecting
+	c.waiters.add(gid)
+
+	defer func() {
+		c.waiters.remove(gid)
+
+		stopUnsub()
+		completeUnsub()
+		errUnsub()
+	}()
+
+	select {
+	case err := <-channel:
+		if err == ErrDownloadError {
+			return c.downloadError(ctx, gid)
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	stopUnsub()
-	completeUnsub()
-	errUnsub()
+// pollForDownload waits for the download by periodically requesting its status.
+func (c *Client) pollForDownload(ctx context.Context, gid string) error {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
 
-	return err
+	for {
+		status, err := c.TellStatusContext(ctx, gid, resultKeys...)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := downloadResult(status); ok {
+			return statusError(status)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // Download adds a new download and waits for it to complete.
 // It returns the status of the finished download.
-func (c *Client) Download(uris []string, options *Options) (status Status, err error) {
+func (c *Client) Download(uris []string, options OptionSet) (status Status, err error) {
 	return c.DownloadWithContext(context.Background(), uris, options)
 }
 
 // DownloadWithContext adds a new download and waits for it to complete.
 // The passed context can be used to cancel the download.
 // It returns the status of the finished download.
-func (c *Client) DownloadWithContext(ctx context.Context, uris []string, options *Options) (status Status, err error) {
-	gid, err := c.AddURI(uris, options)
+func (c *Client) DownloadWithContext(ctx context.Context, uris []string, options OptionSet) (status Status, err error) {
+	gid, err := c.AddURIContext(ctx, uris, options)
 	if err != nil {
 		return
 	}
 
-	downloadDone := make(chan error, 1)
-
-	go func() {
-		downloadDone <- gid.WaitForDownload()
-	}()
+	_ = c.waitForDownload(ctx, gid.GID)
 
-	select {
-	case <-downloadDone:
-		status, err = gid.TellStatus()
-		if err != nil {
-			return
-		}
-	case <-ctx.Done():
+	if ctx.Err() != nil {
 		_ = gid.Delete()
 		err = ctx.Err()
+		return
 	}
 
+	status, err = gid.TellStatusContext(ctx)
 	return
 }
 
 // Delete removes the download denoted by gid and deletes all corresponding files.
 // This is not an aria2 method.
 func (c *Client) Delete(gid string) (err error) {
-	err = c.Remove(gid)
+	return c.DeleteContext(context.Background(), gid)
+}
+
+// DeleteContext is like Delete but the call is canceled when ctx is done.
+func (c *Client) DeleteContext(ctx context.Context, gid string) (err error) {
+	err = c.RemoveContext(ctx, gid)
 	if err != nil {
 		return
 	}
 
-	files, err := c.GetFiles(gid)
+	files, err := c.GetFilesContext(ctx, gid)
 	if err == nil {
 		for _, file := range files {
 			_ = os.Remove(file.Path)
@@ -243,19 +577,16 @@ func (c *Client) getArgs(args ...interface{}) []interface{} {
 // the new download is appended to the end of the queue.
 //
 // This method returns the GID of the newly registered download.
-func (c *Client) AddURIAtPosition(uris []string, position uint, options *Options) (GID, error) {
-	args := c.getArgs(uris)
-
-	if options != nil {
-		args = append(args, options)
-	}
+func (c *Client) AddURIAtPosition(uris []string, position uint, options OptionSet) (GID, error) {
+	return c.AddURIAtPositionContext(context.Background(), uris, position, options)
+}
 
-	if position != QueueEndPosition {
-		args = append(args, position)
-	}
+// AddURIAtPositionContext is like AddURIAtPosition but the call is canceled when ctx is done.
+func (c *Client) AddURIAtPositionContext(ctx context.Context, uris []string, position uint, options OptionSet) (GID, error) {
+	args := c.getArgs(addParams([]interface{}{uris}, position, options)...)
 
 	var reply string
-	err := c.rpcClient.Call(aria2proto.AddURI, args, &reply)
+	err := c.callContext(ctx, aria2proto.AddURI, args, &reply)
 
 	return c.GetGID(reply), err
 }
@@ -270,8 +601,13 @@ func (c *Client) AddURIAtPosition(uris []string, position uint, options *Options
 // The new download is appended to the end of the queue.
 //
 // This method returns the GID of the newly registered download.
-func (c *Client) AddURI(uris []string, options *Options) (GID, error) {
-	return c.AddURIAtPosition(uris, QueueEndPosition, options)
+func (c *Client) AddURI(uris []string, options OptionSet) (GID, error) {
+	return c.AddURIContext(context.Background(), uris, options)
+}
+
+// AddURIContext is like AddURI but the call is canceled when ctx is done.
+func (c *Client) AddURIContext(ctx context.Context, uris []string, options OptionSet) (GID, error) {
+	return c.AddURIAtPositionContext(ctx, uris, QueueEndPosition, options)
 }
 
 // AddTorrentAtPosition adds a BitTorrent download at a specific position in the queue.
@@ -288,20 +624,25 @@ func (c *Client) AddURI(uris []string, options *Options) (GID, error) {
 // the new download is appended to the end of the queue.
 //
 // This method returns the GID of the newly registered download.
-func (c *Client) AddTorrentAtPosition(torrent []byte, uris []string, position uint, options *Options) (GID, error) {
-	encodedTorrent := base64.StdEncoding.EncodeToString(torrent)
-	args := c.getArgs(encodedTorrent, uris)
+// If the connected aria2 build lacks BitTorrent support,
+// a *FeatureError is returned without adding the download.
+// The first call requiring a feature requests the Capabilities of aria2
+// in an additional system.multicall.
+func (c *Client) AddTorrentAtPosition(torrent []byte, uris []string, position uint, options OptionSet) (GID, error) {
+	return c.AddTorrentAtPositionContext(context.Background(), torrent, uris, position, options)
+}
 
-	if options != nil {
-		args = append(args, options)
+// AddTorrentAtPositionContext is like AddTorrentAtPosition but the call is canceled when ctx is done.
+func (c *Client) AddTorrentAtPositionContext(ctx context.Context, torrent []byte, uris []string, position uint, options OptionSet) (GID, error) {
+	if err := c.requireFeature(ctx, FeatureBitTorrent); err != nil {
+		return GID{}, err
 	}
 
-	if position != QueueEndPosition {
-		args = append(args, position)
-	}
+	encodedTorrent := base64.StdEncoding.EncodeToString(torrent)
+	args := c.getArgs(addParams([]interface{}{encodedTorrent, uris}, position, options)...)
 
 	var reply string
-	err := c.rpcClient.Call(aria2proto.AddTorrent, args, &reply)
+	err := c.callContext(ctx, aria2proto.AddTorrent, args, &reply)
 
 	return c.GetGID(reply), err
 }
@@ -318,8 +659,17 @@ func (c *Client) AddTorrentAtPosition(torrent []byte, uris []string, position ui
 // The new download is appended to the end of the queue.
 //
 // This method returns the GID of the newly registered download.
-func (c *Client) AddTorrent(torrent []byte, uris []string, options *Options) (GID, error) {
-	return c.AddTorrentAtPosition(torrent, uris, QueueEndPosition, options)
+// If the connected aria2 build lacks BitTorrent support,
+// a *FeatureError is returned without adding the download.
+// The first call requiring a feature requests the Capabilities of aria2
+// in an additional system.multicall.
+func (c *Client) AddTorrent(torrent []byte, uris []string, options OptionSet) (GID, error) {
+	return c.AddTorrentContext(context.Background(), torrent, uris, options)
+}
+
+// AddTorrentContext is like AddTorrent but the call is canceled when ctx is done.
+func (c *Client) AddTorrentContext(ctx context.Context, torrent []byte, uris []string, options OptionSet) (GID, error) {
+	return c.AddTorrentAtPositionContext(ctx, torrent, uris, QueueEndPosition, options)
 }
 
 // AddMetalinkAtPosition adds a Metalink download at a specific position in the queue by uploading a “.metalink” file.
@@ -330,24 +680,29 @@ func (c *Client) AddTorrent(torrent []byte, uris []string, options *Options) (GI
 // the new download is appended to the end of the queue.
 //
 // This method returns an array of GIDs of newly registered downloads.
-func (c *Client) AddMetalinkAtPosition(metalink []byte, position uint, options *Options) ([]GID, error) {
-	encodedMetalink := base64.StdEncoding.EncodeToString(metalink)
-	args := c.getArgs(encodedMetalink)
+// If the connected aria2 build lacks Metalink support,
+// a *FeatureError is returned without adding the download.
+// The first call requiring a feature requests the Capabilities of aria2
+// in an additional system.multicall.
+func (c *Client) AddMetalinkAtPosition(metalink []byte, position uint, options OptionSet) ([]GID, error) {
+	return c.AddMetalinkAtPositionContext(context.Background(), metalink, position, options)
+}
 
-	if options != nil {
-		args = append(args, options)
+// AddMetalinkAtPositionContext is like AddMetalinkAtPosition but the call is canceled when ctx is done.
+func (c *Client) AddMetalinkAtPositionContext(ctx context.Context, metalink []byte, position uint, options OptionSet) ([]GID, error) {
+	if err := c.requireFeature(ctx, FeatureMetalink); err != nil {
+		return nil, err
 	}
 
-	if position != QueueEndPosition {
-		args = append(args, position)
-	}
+	encodedMetalink := base64.StdEncoding.EncodeToString(metalink)
+	args := c.getArgs(addParams([]interface{}{encodedMetalink}, position, options)...)
 
 	var reply []string
-	err := c.rpcClient.Call(aria2proto.AddMetalink, args, &reply)
+	err := c.callContext(ctx, aria2proto.AddMetalink, args, &reply)
 
 	gids := make([]GID, len(reply))
-	for _, rawGID := range reply {
-		gids = append(gids, c.GetGID(rawGID))
+	for i, rawGID := range reply {
+		gids[i] = c.GetGID(rawGID)
 	}
 
 	return gids, err
@@ -359,15 +714,29 @@ func (c *Client) AddMetalinkAtPosition(metalink []byte, position uint, options *
 // The new download is appended to the end of the queue.
 //
 // This method returns an array of GIDs of newly registered downloads.
-func (c *Client) AddMetalink(metalink []byte, options *Options) ([]GID, error) {
-	return c.AddMetalinkAtPosition(metalink, QueueEndPosition, options)
+// If the connected aria2 build lacks Metalink support,
+// a *FeatureError is returned without adding the download.
+// The first call requiring a feature requests the Capabilities of aria2
+// in an additional system.multicall.
+func (c *Client) AddMetalink(metalink []byte, options OptionSet) ([]GID, error) {
+	return c.AddMetalinkContext(context.Background(), metalink, options)
+}
+
+// AddMetalinkContext is like AddMetalink but the call is canceled when ctx is done.
+func (c *Client) AddMetalinkContext(ctx context.Context, metalink []byte, options OptionSet) ([]GID, error) {
+	return c.AddMetalinkAtPositionContext(ctx, metalink, QueueEndPosition, options)
 }
 
 // Remove removes the download denoted by gid.
 // If the specified download is in progress, it is first stopped.
 // The status of the removed download becomes removed.
 func (c *Client) Remove(gid string) error {
-	return c.rpcClient.Call(aria2proto.Remove, c.getArgs(gid), nil)
+	return c.RemoveContext(context.Background(), gid)
+}
+
+// RemoveContext is like Remove but the call is canceled when ctx is done.
+func (c *Client) RemoveContext(ctx context.Context, gid string) error {
+	return c.callContext(ctx, aria2proto.Remove, c.getArgs(gid), nil)
 }
 
 // ForceRemove removes the download denoted by gid.
@@ -375,7 +744,12 @@ func (c *Client) Remove(gid string) error {
 // without performing any actions which take time, such as contacting BitTorrent trackers to
 // unregister the download first.
 func (c *Client) ForceRemove(gid string) error {
-	return c.rpcClient.Call(aria2proto.ForceRemove, c.getArgs(gid), nil)
+	return c.ForceRemoveContext(context.Background(), gid)
+}
+
+// ForceRemoveContext is like ForceRemove but the call is canceled when ctx is done.
+func (c *Client) ForceRemoveContext(ctx context.Context, gid string) error {
+	return c.callContext(ctx, aria2proto.ForceRemove, c.getArgs(gid), nil)
 }
 
 // Pause pauses the download denoted by gid.
@@ -383,12 +757,22 @@ func (c *Client) ForceRemove(gid string) error {
 // the download is placed in the front of the queue. While the status is paused,
 // the download is not started. To change status to waiting, use the Unpause() method.
 func (c *Client) Pause(gid string) error {
-	return c.rpcClient.Call(aria2proto.Pause, c.getArgs(gid), nil)
+	return c.PauseContext(context.Background(), gid)
+}
+
+// PauseContext is like Pause but the call is canceled when ctx is done.
+func (c *Client) PauseContext(ctx context.Context, gid string) error {
+	return c.callContext(ctx, aria2proto.Pause, c.getArgs(gid), nil)
 }
 
 // PauseAll is equal to calling Pause() for every active/waiting download.
 func (c *Client) PauseAll() error {
-	return c.rpcClient.Call(aria2proto.PauseAll, c.getArgs(), nil)
+	return c.PauseAllContext(context.Background())
+}
+
+// PauseAllContext is like PauseAll but the call is canceled when ctx is done.
+func (c *Client) PauseAllContext(ctx context.Context) error {
+	return c.callContext(ctx, aria2proto.PauseAll, c.getArgs(), nil)
 }
 
 // ForcePause pauses the download denoted by gid.
@@ -396,23 +780,43 @@ func (c *Client) PauseAll() error {
 // without performing any actions which take time, such as contacting BitTorrent trackers to
 // unregister the download first.
 func (c *Client) ForcePause(gid string) error {
-	return c.rpcClient.Call(aria2proto.ForcePause, c.getArgs(gid), nil)
+	return c.ForcePauseContext(context.Background(), gid)
+}
+
+// ForcePauseContext is like ForcePause but the call is canceled when ctx is done.
+func (c *Client) ForcePauseContext(ctx context.Context, gid string) error {
+	return c.callContext(ctx, aria2proto.ForcePause, c.getArgs(gid), nil)
 }
 
 // ForcePauseAll is equal to calling ForcePause() for every active/waiting download.
 func (c *Client) ForcePauseAll() error {
-	return c.rpcClient.Call(aria2proto.ForcePauseAll, c.getArgs(), nil)
+	return c.ForcePauseAllContext(context.Background())
+}
+
+// ForcePauseAllContext is like ForcePauseAll but the call is canceled when ctx is done.
+func (c *Client) ForcePauseAllContext(ctx context.Context) error {
+	return c.callContext(ctx, aria2proto.ForcePauseAll, c.getArgs(), nil)
 }
 
 // Unpause changes the status of the download denoted by gid from paused to waiting,
 // making the download eligible to be restarted.
 func (c *Client) Unpause(gid string) error {
-	return c.rpcClient.Call(aria2proto.Unpause, c.getArgs(gid), nil)
+	return c.UnpauseContext(context.Background(), gid)
+}
+
+// UnpauseContext is like Unpause but the call is canceled when ctx is done.
+func (c *Client) UnpauseContext(ctx context.Context, gid string) error {
+	return c.callContext(ctx, aria2proto.Unpause, c.getArgs(gid), nil)
 }
 
 // UnpauseAll is equal to calling Unpause() for every paused download.
 func (c *Client) UnpauseAll() error {
-	return c.rpcClient.Call(aria2proto.UnpauseAll, c.getArgs(), nil)
+	return c.UnpauseAllContext(context.Background())
+}
+
+// UnpauseAllContext is like UnpauseAll but the call is canceled when ctx is done.
+func (c *Client) UnpauseAllContext(ctx context.Context) error {
+	return c.callContext(ctx, aria2proto.UnpauseAll, c.getArgs(), nil)
 }
 
 // TellStatus returns the progress of the download denoted by gid.
@@ -420,12 +824,17 @@ func (c *Client) UnpauseAll() error {
 // If specified, the returned Status only contains the keys passed to the method.
 // This is useful when you just want specific keys and avoid unnecessary transfers.
 func (c *Client) TellStatus(gid string, keys ...string) (Status, error) {
+	return c.TellStatusContext(context.Background(), gid, keys...)
+}
+
+// TellStatusContext is like TellStatus but the call is canceled when ctx is done.
+func (c *Client) TellStatusContext(ctx context.Context, gid string, keys ...string) (Status, error) {
 	var reply Status
 	// convert nil to empty slice, prevent error The parameter at 1 has wrong type.
 	if len(keys) == 0 {
 		keys = make([]string, 0)
 	}
-	err := c.rpcClient.Call(aria2proto.TellStatus, c.getArgs(gid, keys), &reply)
+	err := c.callContext(ctx, aria2proto.TellStatus, c.getArgs(gid, keys), &reply)
 
 	return reply, err
 }
@@ -433,8 +842,13 @@ func (c *Client) TellStatus(gid string, keys ...string) (Status, error) {
 // GetURIs returns the URIs used in the download denoted by gid.
 // The response is a slice of URIs.
 func (c *Client) GetURIs(gid string) ([]URI, error) {
+	return c.GetURIsContext(context.Background(), gid)
+}
+
+// GetURIsContext is like GetURIs but the call is canceled when ctx is done.
+func (c *Client) GetURIsContext(ctx context.Context, gid string) ([]URI, error) {
 	var reply []URI
-	err := c.rpcClient.Call(aria2proto.GetURIs, c.getArgs(gid), &reply)
+	err := c.callContext(ctx, aria2proto.GetURIs, c.getArgs(gid), &reply)
 
 	return reply, err
 }
@@ -442,18 +856,34 @@ func (c *Client) GetURIs(gid string) ([]URI, error) {
 // GetFiles returns the file list of the download denoted by gid.
 // The response is a slice of Files.
 func (c *Client) GetFiles(gid string) ([]File, error) {
+	return c.GetFilesContext(context.Background(), gid)
+}
+
+// GetFilesContext is like GetFiles but the call is canceled when ctx is done.
+func (c *Client) GetFilesContext(ctx context.Context, gid string) ([]File, error) {
 	var reply []File
-	err := c.rpcClient.Call(aria2proto.GetFiles, c.getArgs(gid), &reply)
+	err := c.callContext(ctx, aria2proto.GetFiles, c.getArgs(gid), &reply)
 
 	return reply, err
 }
 
 // GetPeers returns a list of peers of the download denoted by gid.
-// This method is for BitTorrent only.
+// This method is for BitTorrent only, it returns a *FeatureError
+// if the connected aria2 build lacks BitTorrent support.
+// Like for AddTorrent, this may request the Capabilities first.
 // The response is a slice of Peers.
 func (c *Client) GetPeers(gid string) ([]Peer, error) {
+	return c.GetPeersContext(context.Background(), gid)
+}
+
+// GetPeersContext is like GetPeers but the call is canceled when ctx is done.
+func (c *Client) GetPeersContext(ctx context.Context, gid string) ([]Peer, error) {
+	if err := c.requireFeature(ctx, FeatureBitTorrent); err != nil {
+		return nil, err
+	}
+
 	var reply []Peer
-	err := c.rpcClient.Call(aria2proto.GetPeers, c.getArgs(gid), &reply)
+	err := c.callContext(ctx, aria2proto.GetPeers, c.getArgs(gid), &reply)
 
 	return reply, err
 }
@@ -461,8 +891,13 @@ func (c *Client) GetPeers(gid string) ([]Peer, error) {
 // GetServers returns currently connected HTTP(S)/FTP/SFTP servers of the download denoted by gid.
 // Returns a slice of FileServers.
 func (c *Client) GetServers(gid string) ([]FileServers, error) {
+	return c.GetServersContext(context.Background(), gid)
+}
+
+// GetServersContext is like GetServers but the call is canceled when ctx is done.
+func (c *Client) GetServersContext(ctx context.Context, gid string) ([]FileServers, error) {
 	var reply []FileServers
-	err := c.rpcClient.Call(aria2proto.GetServers, c.getArgs(gid), &reply)
+	err := c.callContext(ctx, aria2proto.GetServers, c.getArgs(gid), &reply)
 
 	return reply, err
 }
@@ -470,8 +905,13 @@ func (c *Client) GetServers(gid string) ([]FileServers, error) {
 // TellActive returns a slice of active downloads represented by their Status.
 // keys does the same as in the TellStatus() method.
 func (c *Client) TellActive(keys ...string) ([]Status, error) {
+	return c.TellActiveContext(context.Background(), keys...)
+}
+
+// TellActiveContext is like TellActive but the call is canceled when ctx is done.
+func (c *Client) TellActiveContext(ctx context.Context, keys ...string) ([]Status, error) {
 	var reply []Status
-	err := c.rpcClient.Call(aria2proto.TellActive, c.getArgs(keys), &reply)
+	err := c.callContext(ctx, aria2proto.TellActive, c.getArgs(keys), &reply)
 
 	return reply, err
 }
@@ -487,8 +927,13 @@ func (c *Client) TellActive(keys ...string) ([]Status, error) {
 //
 // If specified, the returned Statuses only contain the keys passed to the method.
 func (c *Client) TellWaiting(offset int, num uint, keys ...string) ([]Status, error) {
+	return c.TellWaitingContext(context.Background(), offset, num, keys...)
+}
+
+// TellWaitingContext is like TellWaiting but the call is canceled when ctx is done.
+func (c *Client) TellWaitingContext(ctx context.Context, offset int, num uint, keys ...string) ([]Status, error) {
 	var reply []Status
-	err := c.rpcClient.Call(aria2proto.TellWaiting, c.getArgs(offset, num, keys), &reply)
+	err := c.callContext(ctx, aria2proto.TellWaiting, c.getArgs(offset, num, keys), &reply)
 
 	return reply, err
 }
@@ -504,8 +949,13 @@ func (c *Client) TellWaiting(offset int, num uint, keys ...string) ([]Status, er
 //
 // If specified, the returned Statuses only contain the keys passed to the method.
 func (c *Client) TellStopped(offset int, num uint, keys ...string) ([]Status, error) {
+	return c.TellStoppedContext(context.Background(), offset, num, keys...)
+}
+
+// TellStoppedContext is like TellStopped but the call is canceled when ctx is done.
+func (c *Client) TellStoppedContext(ctx context.Context, offset int, num uint, keys ...string) ([]Status, error) {
 	var reply []Status
-	err := c.rpcClient.Call(aria2proto.TellStopped, c.getArgs(offset, num, keys), &reply)
+	err := c.callContext(ctx, aria2proto.TellStopped, c.getArgs(offset, num, keys), &reply)
 
 	return reply, err
 }
@@ -534,13 +984,18 @@ const (
 //
 // The response is an integer denoting the resulting position.
 func (c *Client) ChangePosition(gid string, pos int, how PositionSetBehaviour) (int, error) {
+	return c.ChangePositionContext(context.Background(), gid, pos, how)
+}
+
+// ChangePositionContext is like ChangePosition but the call is canceled when ctx is done.
+func (c *Client) ChangePositionContext(ctx context.Context, gid string, pos int, how PositionSetBehaviour) (int, error) {
 	args := c.getArgs(gid, pos)
 	if how != "" {
 		args = append(args, how)
 	}
 
 	var reply int
-	err := c.rpcClient.Call(aria2proto.ChangePosition, args, &reply)
+	err := c.callContext(ctx, aria2proto.ChangePosition, args, &reply)
 
 	return reply, err
 }
@@ -558,12 +1013,20 @@ func (c *Client) ChangePosition(gid string, pos int, how PositionSetBehaviour) (
 // The first integer is the number of URIs deleted.
 // The second integer is the number of URIs added.
 func (c *Client) ChangeURIAt(gid string, fileIndex uint, delURIs []string, addURIs []string, position uint) (uint, uint, error) {
+	return c.ChangeURIAtContext(context.Background(), gid, fileIndex, delURIs, addURIs, position)
+}
+
+// ChangeURIAtContext is like ChangeURIAt but the call is canceled when ctx is done.
+func (c *Client) ChangeURIAtContext(ctx context.Context, gid string, fileIndex uint, delURIs []string, addURIs []string, position uint) (uint, uint, error) {
 	args := c.getArgs(gid, fileIndex, delURIs, addURIs, position)
 
 	var reply []uint
-	err := c.rpcClient.Call(aria2proto.ChangeURI, args, &reply)
+	err := c.callContext(ctx, aria2proto.ChangeURI, args, &reply)
+	if err != nil {
+		return 0, 0, err
+	}
 
-	return reply[0], reply[1], err
+	return reply[0], reply[1], nil
 }
 
 // ChangeURI removes the URIs in delUris from and appends the URIs in addUris to download denoted by gid.
@@ -580,25 +1043,54 @@ func (c *Client) ChangeURIAt(gid string, fileIndex uint, delURIs []string, addUR
 // The first integer is the number of URIs deleted.
 // The second integer is the number of URIs added.
 func (c *Client) ChangeURI(gid string, fileIndex uint, delURIs []string, addURIs []string) (uint, uint, error) {
+	return c.ChangeURIContext(context.Background(), gid, fileIndex, delURIs, addURIs)
+}
+
+// ChangeURIContext is like ChangeURI but the call is canceled when ctx is done.
+func (c *Client) ChangeURIContext(ctx context.Context, gid string, fileIndex uint, delURIs []string, addURIs []string) (uint, uint, error) {
 	args := c.getArgs(gid, fileIndex, delURIs, addURIs)
 
 	var reply []uint
-	err := c.rpcClient.Call(aria2proto.ChangeURI, args, &reply)
+	err := c.callContext(ctx, aria2proto.ChangeURI, args, &reply)
+	if err != nil {
+		return 0, 0, err
+	}
 
-	return reply[0], reply[1], err
+	return reply[0], reply[1], nil
 }
 
 // GetOptions returns Options of the download denoted by gid.
 // Note that this method does not return options which have no default value and have not been set on the command-line,
 // in configuration files or RPC methods.
 func (c *Client) GetOptions(gid string) (Options, error) {
+	return c.GetOptionsContext(context.Background(), gid)
+}
+
+// GetOptionsContext is like GetOptions but the call is canceled when ctx is done.
+func (c *Client) GetOptionsContext(ctx context.Context, gid string) (Options, error) {
 	var reply Options
-	err := c.rpcClient.Call(aria2proto.GetOptions, c.getArgs(gid), &reply)
+	err := c.callContext(ctx, aria2proto.GetOptions, c.getArgs(gid), &reply)
+
+	return reply, err
+}
+
+// GetOptionValues is like GetOptions but returns the options as OptionValues,
+// which keeps the options which are false or zero.
+func (c *Client) GetOptionValues(gid string) (OptionValues, error) {
+	return c.GetOptionValuesContext(context.Background(), gid)
+}
+
+// GetOptionValuesContext is like GetOptionValues but the call is canceled when ctx is done.
+func (c *Client) GetOptionValuesContext(ctx context.Context, gid string) (OptionValues, error) {
+	var reply OptionValues
+	err := c.callContext(ctx, aria2proto.GetOptions, c.getArgs(gid), &reply)
 
 	return reply, err
 }
 
 // ChangeOptions changes options of the download denoted by gid dynamically.
+// Only the options which are set are changed. Options which are false or zero
+// can only be set using OptionValues.
 //
 // Except for following options, all options are available:
 //   - DryRun
@@ -616,8 +1108,13 @@ func (c *Client) GetOptions(gid string) (Options, error) {
 //   - ForceSave
 //   - MaxDownloadLimit
 //   - MaxUploadLimit
-func (c *Client) ChangeOptions(gid string, options Options) error {
-	return c.rpcClient.Call(aria2proto.ChangeOptions, c.getArgs(gid, options), nil)
+func (c *Client) ChangeOptions(gid string, options OptionSet) error {
+	return c.ChangeOptionsContext(context.Background(), gid, options)
+}
+
+// ChangeOptionsContext is like ChangeOptions but the call is canceled when ctx is done.
+func (c *Client) ChangeOptionsContext(ctx context.Context, gid string, options OptionSet) error {
+	return c.callContext(ctx, aria2proto.ChangeOptions, c.getArgs(gid, optionValues(options)), nil)
 }
 
 // GetGlobalOptions returns the global options.
@@ -626,19 +1123,38 @@ func (c *Client) ChangeOptions(gid string, options Options) error {
 //
 // Because global options are used as a template for the options of newly added downloads,
 // the response contains keys returned by the GetOption() method.
-func (c *Client) GetGlobalOptions() (Options, error) {
-	var reply Options
-	err := c.rpcClient.Call(aria2proto.GetGlobalOptions, c.getArgs(), &reply)
+func (c *Client) GetGlobalOptions() (GlobalOptions, error) {
+	return c.GetGlobalOptionsContext(context.Background())
+}
+
+// GetGlobalOptionsContext is like GetGlobalOptions but the call is canceled when ctx is done.
+func (c *Client) GetGlobalOptionsContext(ctx context.Context) (GlobalOptions, error) {
+	var reply GlobalOptions
+	err := c.callContext(ctx, aria2proto.GetGlobalOptions, c.getArgs(), &reply)
 
 	return reply, err
 }
 
-// TODO global options
+// GetGlobalOptionValues is like GetGlobalOptions but returns the options as OptionValues,
+// which keeps the options which are false or zero.
+func (c *Client) GetGlobalOptionValues() (OptionValues, error) {
+	return c.GetGlobalOptionValuesContext(context.Background())
+}
+
+// GetGlobalOptionValuesContext is like GetGlobalOptionValues but the call is canceled when ctx is done.
+func (c *Client) GetGlobalOptionValuesContext(ctx context.Context) (OptionValues, error) {
+	var reply OptionValues
+	err := c.callContext(ctx, aria2proto.GetGlobalOptions, c.getArgs(), &reply)
+
+	return reply, err
+}
 
 // ChangeGlobalOptions changes global options dynamically.
+// Only the options which are set are changed. Options which are false or zero
+// can only be set using OptionValues.
 //
 // The following global options are available:
-//   - BtMaxOpenFiles
+//   - BTMaxOpenFiles
 //   - DownloadResult
 //   - KeepUnfinishedDownloadResult
 //   - Log
@@ -662,84 +1178,127 @@ func (c *Client) GetGlobalOptions() (Options, error) {
 // With the log option, you can dynamically start logging or change log file.
 // To stop logging, specify an empty string as the parameter value.
 // Note that log file is always opened in append mode.
-func (c *Client) ChangeGlobalOptions(options Options) error {
-	return c.rpcClient.Call(aria2proto.ChangeGlobalOptions, c.getArgs(options), nil)
+//
+// If any other option is set, an *OptionError is returned without calling aria2.
+func (c *Client) ChangeGlobalOptions(options OptionSet) error {
+	return c.ChangeGlobalOptionsContext(context.Background(), options)
+}
+
+// ChangeGlobalOptionsContext is like ChangeGlobalOptions but the call is canceled when ctx is done.
+func (c *Client) ChangeGlobalOptionsContext(ctx context.Context, options OptionSet) error {
+	values := optionValues(options)
+	if err := validateRuntime(values); err != nil {
+		return err
+	}
+
+	return c.callContext(ctx, aria2proto.ChangeGlobalOptions, c.getArgs(values), nil)
 }
 
 // GetGlobalStats returns global statistics such as the overall download and upload speeds.
 func (c *Client) GetGlobalStats() (Stats, error) {
+	return c.GetGlobalStatsContext(context.Background())
+}
+
+// GetGlobalStatsContext is like GetGlobalStats but the call is canceled when ctx is done.
+func (c *Client) GetGlobalStatsContext(ctx context.Context) (Stats, error) {
 	var reply Stats
-	err := c.rpcClient.Call(aria2proto.GetGlobalStats, c.getArgs(), &reply)
+	err := c.callContext(ctx, aria2proto.GetGlobalStats, c.getArgs(), &reply)
 
 	return reply, err
 }
 
 // PurgeDownloadResults purges completed/error/removed downloads to free memory
 func (c *Client) PurgeDownloadResults() error {
-	return c.rpcClient.Call(aria2proto.PurgeDownloadResults, c.getArgs(), nil)
+	return c.PurgeDownloadResultsContext(context.Background())
+}
+
+// PurgeDownloadResultsContext is like PurgeDownloadResults but the call is canceled when ctx is done.
+func (c *Client) PurgeDownloadResultsContext(ctx context.Context) error {
+	return c.callContext(ctx, aria2proto.PurgeDownloadResults, c.getArgs(), nil)
 }
 
 // RemoveDownloadResult removes a completed/error/removed download denoted by gid from memory.
 func (c *Client) RemoveDownloadResult(gid string) error {
-	return c.rpcClient.Call(aria2proto.RemoveDownloadResult, c.getArgs(gid), nil)
+	return c.RemoveDownloadResultContext(context.Background(), gid)
+}
+
+// RemoveDownloadResultContext is like RemoveDownloadResult but the call is canceled when ctx is done.
+func (c *Client) RemoveDownloadResultContext(ctx context.Context, gid string) error {
+	return c.callContext(ctx, aria2proto.RemoveDownloadResult, c.getArgs(gid), nil)
 }
 
 // GetVersion returns the version of aria2 and the list of enabled features.
 func (c *Client) GetVersion() (VersionInfo, error) {
+	return c.GetVersionContext(context.Background())
+}
+
+// GetVersionContext is like GetVersion but the call is canceled when ctx is done.
+func (c *Client) GetVersionContext(ctx context.Context) (VersionInfo, error) {
 	var reply VersionInfo
-	err := c.rpcClient.Call(aria2proto.GetVersion, c.getArgs(), &reply)
+	err := c.callContext(ctx, aria2proto.GetVersion, c.getArgs(), &reply)
 
 	return reply, err
 }
 
 // GetSessionInfo returns session information.
 func (c *Client) GetSessionInfo() (SessionInfo, error) {
+	return c.GetSessionInfoContext(context.Background())
+}
+
+// GetSessionInfoContext is like GetSessionInfo but the call is canceled when ctx is done.
+func (c *Client) GetSessionInfoContext(ctx context.Context) (SessionInfo, error) {
 	var reply SessionInfo
-	err := c.rpcClient.Call(aria2proto.GetSessionInfo, c.getArgs(), &reply)
+	err := c.callContext(ctx, aria2proto.GetSessionInfo, c.getArgs(), &reply)
 
 	return reply, err
 }
 
 // Shutdown shuts down aria2.
 func (c *Client) Shutdown() error {
-	return c.rpcClient.Call(aria2proto.Shutdown, c.getArgs(), nil)
+	return c.ShutdownContext(context.Background())
+}
+
+// ShutdownContext is like Shutdown but the call is canceled when ctx is done.
+func (c *Client) ShutdownContext(ctx context.Context) error {
+	return c.callContext(ctx, aria2proto.Shutdown, c.getArgs(), nil)
 }
 
 // ForceShutdown shuts down aria2.
 // Behaves like the Shutdown() method but doesn't perform any actions which take time,
 // such as contacting BitTorrent trackers to unregister downloads first.
 func (c *Client) ForceShutdown() error {
-	return c.rpcClient.Call(aria2proto.ForceShutdown, c.getArgs(), nil)
+	return c.ForceShutdownContext(context.Background())
+}
+
+// ForceShutdownContext is like ForceShutdown but the call is canceled when ctx is done.
+func (c *Client) ForceShutdownContext(ctx context.Context) error {
+	return c.callContext(ctx, aria2proto.ForceShutdown, c.getArgs(), nil)
 }
 
 // SaveSession saves the current session to a file specified by the SaveSession option.
 func (c *Client) SaveSession() error {
-	return c.rpcClient.Call(aria2proto.SaveSession, c.getArgs(), nil)
+	return c.SaveSessionContext(context.Background())
+}
+
+// SaveSessionContext is like SaveSession but the call is canceled when ctx is done.
+func (c *Client) SaveSessionContext(ctx context.Context) error {
+	return c.callContext(ctx, aria2proto.SaveSession, c.getArgs(), nil)
 }
 
 // MultiCall executes multiple method calls in one request.
 // Returns a MethodResult for each MethodCall in order.
+//
+// The secret token is added to the params of every call which doesn't start with it already,
+// so the calls can be created using NewMethodCall without it.
+// See Batch for a way to build the calls and decode their results.
 func (c *Client) MultiCall(methods ...*MethodCall) ([]MethodResult, error) {
-	var rawResults []json.RawMessage
-	err := c.rpcClient.Call(aria2proto.Multicall, c.getArgs(methods), &rawResults)
-
-	results := make([]MethodResult, len(rawResults))
-
-	for i, rawResult := range rawResults {
-		var methodResult []byte
-
-		var methodErr MethodCallError
-		_ = json.Unmarshal(rawResult, &methodErr)
-
-		if methodErr == (MethodCallError{}) {
-			var resultArray [1]json.RawMessage
-			_ = json.Unmarshal(rawResult, &resultArray)
-
-			methodResult = resultArray[0]
-		}
+	return c.MultiCallContext(context.Background(), methods...)
+}
 
-		results[i] = MethodResult{Result: methodResult, Error: &methodErr}
-	}
+// MultiCallContext is like MultiCall but the call is canceled when ctx is done.
+func (c *Client) MultiCallContext(ctx context.Context, methods ...*MethodCall) ([]MethodResult, error) {
+	var rawResults []json.RawMessage
+	err := c.callContext(ctx, aria2proto.Multicall, c.multicallArgs(methods), &rawResults)
 
-	return results, err
+	return decodeMethodResults(rawResults), err
 }
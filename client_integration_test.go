@@ -0,0 +1,19 @@
+package arigo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddMetalink(t *testing.T) {
+	server, client := newTestClient(t)
+
+	// the GIDs used to be appended to a slice which already had
+	// the right length, so they came after as many empty GIDs
+	gids, err := client.AddMetalink([]byte("<metalink/>"), nil)
+	require.NoError(t, err)
+	require.Len(t, gids, 1)
+	assert.Equal(t, server.Downloads(), []string{gids[0].GID})
+}
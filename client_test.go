@@ -1,6 +1,19 @@
 package arigo
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 // Dial is a convenience method which connects to an aria2 RPC interface.
 // It establishes a WebSocket connection to the given url and passes it
@@ -45,3 +58,162 @@ func ExampleClient() {
 
 	fmt.Println(status.Status)
 }
+
+func TestDialHTTP(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64        `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "aria2.tellStatus", req.Method)
+		assert.Equal(t, "token:secret", req.Params[0])
+
+		status := StatusActive
+		if atomic.AddInt32(&calls, 1) >= 3 {
+			status = StatusCompleted
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     req.ID,
+			"result": map[string]interface{}{"gid": req.Params[1], "status": status},
+		})
+	}))
+	defer server.Close()
+
+	client, err := DialHTTP(server.URL+"/jsonrpc", "secret", WithPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, time.Millisecond, client.pollInterval)
+
+	assert.NoError(t, client.WaitForDownload("2089b05ecca3d829"))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+}
+
+func TestDialXMLRPC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+		<methodResponse><params><param><value><struct>
+			<member><name>gid</name><value><string>2089b05ecca3d829</string></value></member>
+			<member><name>status</name><value><string>complete</string></value></member>
+			<member><name>completedLength</name><value><string>901120</string></value></member>
+		</struct></value></param></params></methodResponse>`))
+	}))
+	defer server.Close()
+
+	client, err := DialXMLRPC(server.URL+"/rpc", "")
+	require.NoError(t, err)
+	defer client.Close()
+
+	status, err := client.TellStatus("2089b05ecca3d829")
+	require.NoError(t, err)
+	assert.Equal(t, "2089b05ecca3d829", status.GID)
+	assert.Equal(t, StatusCompleted, status.Status)
+	assert.EqualValues(t, 901120, status.CompletedLength)
+}
+
+func TestCallContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := DialHTTP(server.URL+"/jsonrpc", "")
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = client.TellStatusContext(ctx, "2089b05ecca3d829")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	gid := client.GetGID("2089b05ecca3d829")
+	_, err = gid.TellStatusContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestClientDone(t *testing.T) {
+	client, err := DialHTTP("http://localhost:6800/jsonrpc", "")
+	require.NoError(t, err)
+
+	assert.NoError(t, client.Err())
+	require.NoError(t, client.Close())
+
+	<-client.Done()
+	assert.Equal(t, ErrClientClosed, client.Err())
+}
+
+func TestCallContextCancelsRequest(t *testing.T) {
+	canceled := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// aria2 hangs, the body is read so the server notices when the client gives up
+		_, _ = ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+		canceled <- struct{}{}
+	}))
+	defer server.Close()
+
+	httpClient, err := DialHTTP(server.URL+"/jsonrpc", "")
+	require.NoError(t, err)
+	defer httpClient.Close()
+
+	xmlClient, err := DialXMLRPC(server.URL+"/rpc", "")
+	require.NoError(t, err)
+	defer xmlClient.Close()
+
+	for _, client := range []*Client{httpClient, xmlClient} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err = client.GetVersionContext(ctx)
+		cancel()
+		assert.Equal(t, context.DeadlineExceeded, err)
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("request wasn't canceled")
+		}
+	}
+}
+
+func TestCloseCancelsRequests(t *testing.T) {
+	received := make(chan struct{}, 2)
+	canceled := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		received <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			canceled <- struct{}{}
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	httpClient, err := DialHTTP(server.URL+"/jsonrpc", "")
+	require.NoError(t, err)
+
+	xmlClient, err := DialXMLRPC(server.URL+"/rpc", "")
+	require.NoError(t, err)
+
+	for _, client := range []*Client{httpClient, xmlClient} {
+		go func(client *Client) {
+			_, _ = client.GetVersion()
+		}(client)
+		<-received
+
+		require.NoError(t, client.Close())
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("request wasn't canceled")
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package arigo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ConfigLine is a line of an aria2 configuration file.
+type ConfigLine struct {
+	Key   string // Name of the option, empty for comments and blank lines
+	Value string // Value of the option
+	Text  string // The line itself if it's a comment or blank
+}
+
+// IsOption reports whether the line sets an option.
+func (line ConfigLine) IsOption() bool {
+	return line.Key != ""
+}
+
+func (line ConfigLine) String() string {
+	if line.IsOption() {
+		return line.Key + "=" + line.Value
+	}
+	return line.Text
+}
+
+// Config is an aria2 configuration file like aria2.conf.
+// It consists of "key=value" lines, comments starting with "#" and blank lines.
+// Keys are the names of the options, like "max-concurrent-downloads".
+// Options which can be given multiple times, like header, may be repeated.
+//
+// The comments, blank lines and the order of the lines are preserved,
+// so a file can be read, changed and written back without losing anything.
+//
+// Config implements OptionSet, so a file can be applied using ChangeGlobalOptions:
+//
+//	config, err := arigo.ReadConfigFile("aria2.conf")
+//	if err != nil {
+//		// handle error
+//	}
+//	err = client.ChangeGlobalOptions(config.Values().ChangeableGlobal())
+type Config struct {
+	Lines []ConfigLine
+}
+
+// NewConfig creates a configuration setting the options, sorted by name.
+func NewConfig(options OptionSet) *Config {
+	config := &Config{}
+	config.Update(options)
+	return config
+}
+
+// ReadConfig reads a configuration in the format of aria2.conf.
+func ReadConfig(r io.Reader) (*Config, error) {
+	config := &Config{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			config.Lines = append(config.Lines, ConfigLine{Text: text})
+			continue
+		}
+
+		i := strings.IndexByte(trimmed, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected key=value but got %q", n, text)
+		}
+
+		config.Lines = append(config.Lines, ConfigLine{
+			Key:   strings.TrimSpace(trimmed[:i]),
+			Value: strings.TrimSpace(trimmed[i+1:]),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ReadConfigFile reads the configuration file at path.
+func ReadConfigFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadConfig(f)
+}
+
+// WriteTo writes the configuration to w in the format of aria2.conf.
+func (config *Config) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, line := range config.Lines {
+		buf.WriteString(line.String())
+		buf.WriteByte('\n')
+	}
+
+	return buf.WriteTo(w)
+}
+
+// WriteFile writes the configuration to the file at path,
+// which is created if it doesn't exist.
+func (config *Config) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if _, err := config.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Get returns the value of the option and whether it's set.
+// If the option is repeated, the last value is returned.
+func (config *Config) Get(key string) (string, bool) {
+	values := config.GetAll(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetAll returns all values of the option in the order of the lines.
+func (config *Config) GetAll(key string) []string {
+	var values []string
+	for _, line := range config.Lines {
+		if line.Key == key {
+			values = append(values, line.Value)
+		}
+	}
+	return values
+}
+
+// Set sets the option to the values.
+// The first line of the option is replaced, the other ones are removed.
+// If the option isn't set yet, it's appended to the end.
+func (config *Config) Set(key string, values ...string) {
+	lines := make([]ConfigLine, 0, len(config.Lines)+len(values))
+	replaced := false
+	for _, line := range config.Lines {
+		if line.Key != key {
+			lines = append(lines, line)
+			continue
+		}
+
+		if !replaced {
+			lines = appendOptionLines(lines, key, values)
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		lines = appendOptionLines(lines, key, values)
+	}
+
+	config.Lines = lines
+}
+
+func appendOptionLines(lines []ConfigLine, key string, values []string) []ConfigLine {
+	for _, value := range values {
+		lines = append(lines, ConfigLine{Key: key, Value: value})
+	}
+	return lines
+}
+
+// Add adds a line setting the option to value, keeping the existing lines of the option.
+func (config *Config) Add(key, value string) {
+	config.Lines = append(config.Lines, ConfigLine{Key: key, Value: value})
+}
+
+// AddComment adds a comment line. The "#" is added to text.
+func (config *Config) AddComment(text string) {
+	config.Lines = append(config.Lines, ConfigLine{Text: "# " + text})
+}
+
+// Delete removes all lines of the option.
+func (config *Config) Delete(key string) {
+	config.Set(key)
+}
+
+// Update sets all options of options, sorted by name.
+// Options which can be given multiple times, like header,
+// are written as one line per value.
+func (config *Config) Update(options OptionSet) {
+	values := optionValues(options)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if multiValueOptions[key] {
+			config.Set(key, splitLines(values[key])...)
+		} else {
+			config.Set(key, values[key])
+		}
+	}
+}
+
+// Values returns the options set by the configuration.
+// The values of repeated options which can be given multiple times
+// are joined with newlines, for all other options the last value wins.
+func (config *Config) Values() OptionValues {
+	values := OptionValues{}
+	for _, line := range config.Lines {
+		if !line.IsOption() {
+			continue
+		}
+
+		if previous, ok := values[line.Key]; ok && multiValueOptions[line.Key] {
+			values[line.Key] = previous + "\n" + line.Value
+		} else {
+			values[line.Key] = line.Value
+		}
+	}
+	return values
+}
+
+// Options decodes the options of the configuration, see OptionValues.Options.
+func (config *Config) Options() (Options, error) {
+	return config.Values().Options()
+}
+
+// GlobalOptions decodes the options of the configuration, see OptionValues.GlobalOptions.
+func (config *Config) GlobalOptions() (GlobalOptions, error) {
+	return config.Values().GlobalOptions()
+}
@@ -0,0 +1,32 @@
+package arigo_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	_, client := newTestClient(t)
+
+	config, err := arigo.ReadConfig(strings.NewReader("enable-rpc=true\nrpc-listen-port=6800\n# downloads\nmax-concurrent-downloads=2\ncontinue=false\n"))
+	require.NoError(t, err)
+
+	err = client.ChangeGlobalOptions(config)
+	assert.True(t, errors.Is(err, arigo.ErrOptionNotChangeable))
+	require.NoError(t, client.ChangeGlobalOptions(config.Values().ChangeableGlobal()))
+
+	values, err := client.GetGlobalOptionValues()
+	require.NoError(t, err)
+	assert.Equal(t, "2", values["max-concurrent-downloads"])
+	assert.Equal(t, "false", values["continue"])
+
+	dumped := arigo.NewConfig(values)
+	value, ok := dumped.Get("continue")
+	assert.True(t, ok)
+	assert.Equal(t, "false", value)
+}
@@ -0,0 +1,96 @@
+package arigo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `# aria2 configuration
+dir=/downloads
+
+max-concurrent-downloads = 3
+header=X-A: 1
+header=X-B: 2
+  # limits
+max-overall-download-limit=1M
+continue=false
+enable-rpc=true
+`
+
+func TestReadConfig(t *testing.T) {
+	config, err := ReadConfig(strings.NewReader(testConfig))
+	require.NoError(t, err)
+
+	value, ok := config.Get("max-concurrent-downloads")
+	assert.True(t, ok)
+	assert.Equal(t, "3", value)
+	assert.Equal(t, []string{"X-A: 1", "X-B: 2"}, config.GetAll("header"))
+
+	assert.Equal(t, OptionValues{
+		"dir":                        "/downloads",
+		"max-concurrent-downloads":   "3",
+		"header":                     "X-A: 1\nX-B: 2",
+		"max-overall-download-limit": "1M",
+		"continue":                   "false",
+		"enable-rpc":                 "true",
+	}, config.Values())
+
+	options, err := config.GlobalOptions()
+	require.NoError(t, err)
+	assert.Equal(t, GlobalOptions{
+		Options:                 Options{Dir: "/downloads", Header: Headers{"X-A: 1", "X-B: 2"}},
+		MaxConcurrentDownloads:  3,
+		MaxOverallDownloadLimit: Rate(Mebibyte),
+		EnableRPC:               true,
+	}, options)
+
+	assert.NotContains(t, config.Values().ChangeableGlobal(), "enable-rpc")
+	assert.NoError(t, validateRuntime(config.Values().ChangeableGlobal()))
+
+	_, err = ReadConfig(strings.NewReader("dir=/tmp\ninvalid\n"))
+	assert.EqualError(t, err, `line 2: expected key=value but got "invalid"`)
+}
+
+func TestWriteConfig(t *testing.T) {
+	config, err := ReadConfig(strings.NewReader(testConfig))
+	require.NoError(t, err)
+
+	config.Set("max-concurrent-downloads", "5")
+	config.Set("header", "X-C: 3")
+	config.Delete("enable-rpc")
+	config.Add("split", "4")
+
+	var buf bytes.Buffer
+	_, err = config.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, `# aria2 configuration
+dir=/downloads
+
+max-concurrent-downloads=5
+header=X-C: 3
+  # limits
+max-overall-download-limit=1M
+continue=false
+split=4
+`, buf.String())
+}
+
+func TestNewConfig(t *testing.T) {
+	config := NewConfig(GlobalOptions{
+		Options:                 Options{Header: Headers{"X-A: 1", "X-B: 2"}, Split: 4},
+		MaxOverallDownloadLimit: Rate(Mebibyte),
+	})
+
+	var buf bytes.Buffer
+	_, err := config.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "header=X-A: 1\nheader=X-B: 2\nmax-overall-download-limit=1048576\nsplit=4\n", buf.String())
+
+	read, err := ReadConfig(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, config, read)
+}
@@ -0,0 +1,390 @@
+package arigo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStartTimeout is the time a Daemon waits for aria2 to become ready
+	// if Daemon.StartTimeout is not set.
+	DefaultStartTimeout = 10 * time.Second
+
+	// DefaultKillTimeout is the time a Daemon waits for aria2 to exit
+	// after ForceShutdown before killing the process if Daemon.KillTimeout is not set.
+	DefaultKillTimeout = 5 * time.Second
+)
+
+var (
+	// ErrDaemonStarted is returned when Start is called on a Daemon that was already started.
+	ErrDaemonStarted = errors.New("daemon already started")
+	// ErrDaemonNotStarted is returned when a Daemon that wasn't started is stopped.
+	ErrDaemonNotStarted = errors.New("daemon not started")
+)
+
+// Daemon starts and manages an aria2c process.
+// The process is started with the RPC interface enabled on a free port
+// and protected by a random secret.
+//
+// The zero value is ready to use.
+type Daemon struct {
+	// Path of the aria2c executable.
+	// If empty, aria2c is looked up in the directories named by the PATH environment variable.
+	Path string
+
+	// Args are additional command line arguments passed to aria2c.
+	// The RPC options are set by the Daemon and must not be included.
+	Args []string
+
+	// Logger receives the output of aria2c line by line.
+	// If nil, the output is discarded.
+	Logger *log.Logger
+
+	// StartTimeout limits the time Start waits for the RPC interface to become ready.
+	// If zero, DefaultStartTimeout is used.
+	StartTimeout time.Duration
+
+	// KillTimeout is the time to wait for aria2 to exit after a forced shutdown
+	// before the process is killed.
+	// If zero, DefaultKillTimeout is used.
+	KillTimeout time.Duration
+
+	mut    sync.Mutex
+	proc   *daemonProcess // running process, nil if not started
+	last   *daemonProcess // most recently started process
+	client *Client        // nil while the process is starting
+}
+
+// daemonProcess is an aria2c process started by a Daemon.
+type daemonProcess struct {
+	cmd    *exec.Cmd
+	port   int
+	secret string
+	exited chan struct{}
+	err    error // exit error, set before exited is closed
+}
+
+func (p *daemonProcess) url() string {
+	return "ws://127.0.0.1:" + strconv.Itoa(p.port) + "/jsonrpc"
+}
+
+// Start starts aria2c and waits until its RPC interface answers.
+// It returns a client which is connected to it.
+// The client is closed when the daemon is shut down.
+// Once aria2 was shut down, the daemon can be started again.
+//
+// If ctx is done before aria2 is ready, the process is killed and ctx.Err() is returned.
+func (d *Daemon) Start(ctx context.Context) (*Client, error) {
+	proc, err := d.startProcess()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := d.StartTimeout
+	if timeout == 0 {
+		timeout = DefaultStartTimeout
+	}
+
+	readyCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the lock isn't held while waiting for aria2,
+	// so URL and Secret don't block and Shutdown can abort the start.
+	client, err := proc.connect(readyCtx)
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if err == nil && d.proc != proc {
+		_ = client.Close()
+		err = errors.New("daemon was shut down while starting")
+	}
+	if err != nil {
+		_ = proc.cmd.Process.Kill()
+		<-proc.exited
+		if d.proc == proc {
+			d.proc = nil
+		}
+		return nil, err
+	}
+
+	d.client = client
+	return client, nil
+}
+
+// startProcess starts the aria2c process.
+func (d *Daemon) startProcess() (*daemonProcess, error) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.proc != nil {
+		return nil, ErrDaemonStarted
+	}
+
+	path := d.Path
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath("aria2c"); err != nil {
+			return nil, err
+		}
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{
+		"--enable-rpc",
+		"--rpc-listen-port=" + strconv.Itoa(port),
+		"--rpc-secret=" + secret,
+		// make sure aria2 doesn't outlive us
+		"--stop-with-process=" + strconv.Itoa(os.Getpid()),
+	}, d.Args...)
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &lineLogger{logger: d.Logger, prefix: "aria2c: "}
+	cmd.Stderr = &lineLogger{logger: d.Logger, prefix: "aria2c: "}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	proc := &daemonProcess{
+		cmd:    cmd,
+		port:   port,
+		secret: secret,
+		exited: make(chan struct{}),
+	}
+
+	go func() {
+		proc.err = cmd.Wait()
+		close(proc.exited)
+	}()
+
+	d.proc = proc
+	d.last = proc
+	return proc, nil
+}
+
+// connect connects to the RPC interface as soon as it's ready.
+func (p *daemonProcess) connect(ctx context.Context) (*Client, error) {
+	url := p.url()
+
+	for {
+		client, err := DialContext(ctx, url, p.secret)
+		if err == nil {
+			if _, err = client.GetVersionContext(ctx); err == nil {
+				return client, nil
+			}
+			_ = client.Close()
+		}
+
+		select {
+		case <-p.exited:
+			return nil, fmt.Errorf("aria2c exited before it was ready: %v", p.err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// URL returns the WebSocket url of the RPC interface.
+// It returns an empty string if the daemon wasn't started.
+func (d *Daemon) URL() string {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.proc == nil {
+		return ""
+	}
+	return d.proc.url()
+}
+
+// Secret returns the secret authorization token of the RPC interface.
+// It can be used to create more clients using Dial.
+// It returns an empty string if the daemon wasn't started.
+func (d *Daemon) Secret() string {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.proc == nil {
+		return ""
+	}
+	return d.proc.secret
+}
+
+// Shutdown shuts down aria2 gracefully and waits for the process to exit.
+// If aria2 doesn't exit before ctx is done, it's shut down using ForceShutdown.
+// If ctx has no deadline, aria2 has KillTimeout to answer the shutdown request.
+//
+// If the daemon is still starting, the process is killed.
+func (d *Daemon) Shutdown(ctx context.Context) error {
+	return d.stop(ctx, false)
+}
+
+// ForceShutdown shuts down aria2 without performing any actions which take time
+// and waits for the process to exit.
+// If aria2 still doesn't exit within KillTimeout, the process is killed.
+func (d *Daemon) ForceShutdown() error {
+	return d.stop(context.Background(), true)
+}
+
+func (d *Daemon) stop(ctx context.Context, force bool) error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	proc := d.proc
+	if proc == nil {
+		return ErrDaemonNotStarted
+	}
+
+	err := d.terminate(ctx, proc, force)
+	if d.client != nil {
+		_ = d.client.Close()
+		d.client = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	d.proc = nil
+	return nil
+}
+
+// terminate shuts down the process and waits for it to exit.
+// If the daemon is still starting, there's no client yet
+// and the process is killed right away.
+func (d *Daemon) terminate(ctx context.Context, proc *daemonProcess, force bool) error {
+	timeout := d.KillTimeout
+	if timeout == 0 {
+		timeout = DefaultKillTimeout
+	}
+
+	if d.client != nil && !force {
+		shutdownCtx, cancel := ctx, context.CancelFunc(func() {})
+		if _, ok := ctx.Deadline(); !ok {
+			// a hanging aria2 would otherwise block forever
+			shutdownCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		err := d.client.ShutdownContext(shutdownCtx)
+		cancel()
+
+		if err == nil && proc.waitExit(ctx) {
+			return nil
+		}
+	}
+
+	if d.client != nil {
+		forceCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if d.client.ForceShutdownContext(forceCtx) == nil && proc.waitExit(forceCtx) {
+			return nil
+		}
+	}
+
+	if err := proc.cmd.Process.Kill(); err != nil {
+		select {
+		case <-proc.exited:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	<-proc.exited
+	return nil
+}
+
+// waitExit waits for the process to exit.
+// It returns false if ctx is done first.
+func (p *daemonProcess) waitExit(ctx context.Context) bool {
+	select {
+	case <-p.exited:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Wait waits for the aria2c process to exit and returns its exit error.
+// Note that aria2 exits with UnfinishedDownloads if there were unfinished
+// downloads when it was shut down.
+func (d *Daemon) Wait() error {
+	d.mut.Lock()
+	proc := d.last
+	d.mut.Unlock()
+
+	if proc == nil {
+		return ErrDaemonNotStarted
+	}
+
+	<-proc.exited
+	return proc.err
+}
+
+// freePort returns a TCP port which is currently free.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// lineLogger is a writer which logs every line written to it.
+type lineLogger struct {
+	logger *log.Logger
+	prefix string
+	buf    []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	if l.logger == nil {
+		return len(p), nil
+	}
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := bytes.TrimRight(l.buf[:i], "\r")
+		if len(line) > 0 {
+			l.logger.Print(l.prefix + string(line))
+		}
+		l.buf = l.buf[i+1:]
+	}
+
+	return len(p), nil
+}
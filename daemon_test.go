@@ -0,0 +1,161 @@
+package arigo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAria2cEnv makes the test binary act as aria2c, see fakeAria2c.
+const fakeAria2cEnv = "ARIGO_FAKE_ARIA2C"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeAria2cEnv); mode != "" {
+		fakeAria2c(mode, os.Args[1:])
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeAria2c serves the RPC methods used by Daemon on the port given by args.
+// If mode is "hang", it never starts listening.
+func fakeAria2c(mode string, args []string) {
+	if mode == "hang" {
+		time.Sleep(time.Minute)
+		return
+	}
+
+	var port string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--rpc-listen-port=") {
+			port = strings.TrimPrefix(arg, "--rpc-listen-port=")
+		}
+	}
+
+	upgrader := websocket.Upgrader{}
+	http.HandleFunc("/jsonrpc", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for {
+			var req struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+			}
+			if err := ws.ReadJSON(&req); err != nil {
+				return
+			}
+
+			var result interface{} = "OK"
+			if req.Method == "aria2.getVersion" {
+				result = VersionInfo{Version: "1.36.0", EnabledFeatures: []string{}}
+			}
+			_ = ws.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+
+			if strings.HasSuffix(strings.ToLower(req.Method), "shutdown") {
+				os.Exit(0)
+			}
+		}
+	})
+
+	log.Fatal(http.ListenAndServe("127.0.0.1:"+port, nil))
+}
+
+func TestLineLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := &lineLogger{logger: log.New(&buf, "", 0), prefix: "aria2c: "}
+
+	_, _ = l.Write([]byte("first\nsec"))
+	_, _ = l.Write([]byte("ond\r\n\nthird"))
+
+	assert.Equal(t, "aria2c: first\naria2c: second\n", buf.String())
+}
+
+func TestDaemonExitsEarly(t *testing.T) {
+	path, err := exec.LookPath("false")
+	if err != nil {
+		t.Skip("false not available")
+	}
+
+	d := Daemon{Path: path}
+	_, err = d.Start(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exited before it was ready")
+
+	assert.Error(t, d.Wait())
+}
+
+func TestDaemonNotStarted(t *testing.T) {
+	var d Daemon
+
+	assert.Equal(t, ErrDaemonNotStarted, d.Shutdown(context.Background()))
+	assert.Equal(t, ErrDaemonNotStarted, d.Wait())
+	assert.Equal(t, "", d.URL())
+}
+
+func TestDaemonRestart(t *testing.T) {
+	_ = os.Setenv(fakeAria2cEnv, "serve")
+	defer os.Unsetenv(fakeAria2cEnv)
+
+	d := Daemon{Path: os.Args[0]}
+	for i := 0; i < 2; i++ {
+		client, err := d.Start(context.Background())
+		require.NoError(t, err)
+		assert.NotEmpty(t, d.URL())
+		assert.NotEmpty(t, d.Secret())
+
+		_, err = client.GetVersion()
+		assert.NoError(t, err)
+
+		require.NoError(t, d.Shutdown(context.Background()))
+		assert.NoError(t, d.Wait())
+		assert.Error(t, client.Err())
+
+		assert.Equal(t, ErrDaemonNotStarted, d.Shutdown(context.Background()))
+		assert.Equal(t, "", d.URL())
+		assert.Equal(t, "", d.Secret())
+	}
+}
+
+func TestDaemonShutdownWhileStarting(t *testing.T) {
+	_ = os.Setenv(fakeAria2cEnv, "hang")
+	defer os.Unsetenv(fakeAria2cEnv)
+
+	d := Daemon{Path: os.Args[0]}
+	started := make(chan error, 1)
+	go func() {
+		_, err := d.Start(context.Background())
+		started <- err
+	}()
+
+	// URL doesn't block while aria2 is starting
+	for d.URL() == "" {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NotEmpty(t, d.Secret())
+
+	require.NoError(t, d.Shutdown(context.Background()))
+	select {
+	case err := <-started:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Start didn't return")
+	}
+
+	assert.Equal(t, ErrDaemonNotStarted, d.Shutdown(context.Background()))
+}
@@ -0,0 +1,197 @@
+package arigo
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrTransportNotConfigurable is returned when WithTLSConfig or WithProxy is used
+// with an http.Client whose transport isn't an *http.Transport.
+var ErrTransportNotConfigurable = errors.New("transport not configurable")
+
+// DialOption configures how the connection to aria2 is established
+// and how the client uses it.
+//
+// WithHandshakeTimeout, WithCompression, WithBufferSizes and WithKeepAlive
+// only apply to WebSocket connections, WithPollInterval only applies to
+// the HTTP and XML-RPC transports. All other options apply to every transport.
+type DialOption func(*dialOptions)
+
+type dialOptions struct {
+	dialer websocket.Dialer
+	header http.Header
+
+	keepAliveInterval time.Duration
+	keepAliveTimeout  time.Duration
+
+	batchWindow  time.Duration
+	maxBatchSize int
+
+	pollInterval time.Duration
+}
+
+func newDialOptions(opts []DialOption) dialOptions {
+	o := dialOptions{header: http.Header{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// apply configures the client which uses the connection.
+func (o dialOptions) apply(c *Client) {
+	// clients receiving events don't poll
+	if o.pollInterval > 0 && c.pollInterval > 0 {
+		c.pollInterval = o.pollInterval
+	}
+	if o.batchWindow > 0 {
+		c.batcher = newBatcher(c, o.batchWindow, o.maxBatchSize)
+	}
+}
+
+// httpClient returns the client used by the HTTP based transports.
+// If TLS or proxy settings are given, the returned client uses a copy of the
+// transport of client with them applied. client itself is never modified.
+func (o dialOptions) httpClient(client *http.Client) (*http.Client, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if o.dialer.TLSClientConfig == nil && o.dialer.Proxy == nil {
+		return client, nil
+	}
+
+	roundTripper := client.Transport
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+	base, ok := roundTripper.(*http.Transport)
+	if !ok {
+		return nil, ErrTransportNotConfigurable
+	}
+
+	transport := base.Clone()
+	if o.dialer.TLSClientConfig != nil {
+		transport.TLSClientConfig = o.dialer.TLSClientConfig
+	}
+	if o.dialer.Proxy != nil {
+		transport.Proxy = o.dialer.Proxy
+	}
+
+	c := *client
+	c.Transport = transport
+	return &c, nil
+}
+
+// WithTLSConfig sets the TLS configuration used for "wss" and "https" urls.
+// Use it to provide client certificates or trust a custom CA.
+func WithTLSConfig(config *tls.Config) DialOption {
+	return func(o *dialOptions) {
+		o.dialer.TLSClientConfig = config
+	}
+}
+
+// WithHeader adds a header to the opening handshake request,
+// or to every request of the HTTP and XML-RPC transports.
+// It can be used multiple times, even for the same key.
+func WithHeader(key, value string) DialOption {
+	return func(o *dialOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithBasicAuth sets the Authorization header added by WithHeader
+// to use HTTP Basic Authentication with the given credentials.
+// This is useful when aria2 is behind a reverse proxy which requires authentication.
+// It's not related to the authToken used by aria2.
+func WithBasicAuth(username, password string) DialOption {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return func(o *dialOptions) {
+		o.header.Set("Authorization", "Basic "+credentials)
+	}
+}
+
+// WithProxy sets the function which returns the proxy to use for a request.
+// http.ProxyFromEnvironment and http.ProxyURL can be used to create it.
+// By default no proxy is used for WebSocket connections and the HTTP and XML-RPC
+// transports use the proxy of their http.Client.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) DialOption {
+	return func(o *dialOptions) {
+		o.dialer.Proxy = proxy
+	}
+}
+
+// WithHandshakeTimeout sets the maximum duration for the opening handshake to complete.
+// By default the handshake is only limited by the context passed to DialContext.
+func WithHandshakeTimeout(timeout time.Duration) DialOption {
+	return func(o *dialOptions) {
+		o.dialer.HandshakeTimeout = timeout
+	}
+}
+
+// WithCompression specifies whether per message compression should be negotiated with the server.
+func WithCompression(enable bool) DialOption {
+	return func(o *dialOptions) {
+		o.dialer.EnableCompression = enable
+	}
+}
+
+// WithBufferSizes sets the I/O buffer sizes of the connection in bytes.
+// If a size is zero, a default size of 4096 bytes is used.
+func WithBufferSizes(readSize, writeSize int) DialOption {
+	return func(o *dialOptions) {
+		o.dialer.ReadBufferSize = readSize
+		o.dialer.WriteBufferSize = writeSize
+	}
+}
+
+// WithKeepAlive makes the client send a WebSocket ping every interval.
+// If nothing is received from aria2 for longer than timeout, the connection
+// is considered dead and closed. The client then reports ErrKeepAliveTimeout,
+// or reconnects if it was created by DialReconnecting.
+//
+// timeout has to be larger than interval because the pongs are what keeps
+// the connection alive. A timeout of zero or one which isn't larger than
+// interval is replaced by three times the interval.
+// By default no pings are sent.
+func WithKeepAlive(interval, timeout time.Duration) DialOption {
+	return func(o *dialOptions) {
+		o.keepAliveInterval = interval
+		o.keepAliveTimeout = timeout
+	}
+}
+
+// WithBatching makes the client coalesce the calls made within window
+// into a single system.multicall request, which saves a round trip per call
+// when many calls are made concurrently.
+// The first call of a batch waits up to window for others to join it.
+// If a batch reaches maxSize calls, it's sent right away. A maxSize of zero
+// or less doesn't limit the size of a batch.
+//
+// Errors reported by aria2 for batched calls are a *MethodCallError
+// instead of a *RPCError, both match the same sentinel errors using errors.Is.
+// MultiCall and Batch are never coalesced with other calls.
+//
+// By default every call is sent on its own.
+func WithBatching(window time.Duration, maxSize int) DialOption {
+	return func(o *dialOptions) {
+		o.batchWindow = window
+		o.maxBatchSize = maxSize
+	}
+}
+
+// WithPollInterval sets how often the status of a download is polled
+// while waiting for it to finish. It only applies to the HTTP and XML-RPC
+// transports, which don't receive events.
+//
+// By default DefaultPollInterval is used.
+func WithPollInterval(interval time.Duration) DialOption {
+	return func(o *dialOptions) {
+		o.pollInterval = interval
+	}
+}
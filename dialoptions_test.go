@@ -0,0 +1,168 @@
+package arigo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialOptions(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			_ = ws.Close()
+		}
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client, err := Dial(url, "",
+		WithHeader("X-Custom", "a"),
+		WithHeader("X-Custom", "b"),
+		WithBasicAuth("user", "pass"),
+		WithBufferSizes(1024, 1024),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	r := <-received
+	assert.Equal(t, []string{"a", "b"}, r.Header["X-Custom"])
+
+	username, password, ok := r.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+}
+
+func TestDialOptionsHTTP(t *testing.T) {
+	received := make(chan *http.Request, 1)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		received <- r
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"OK"}`, req.ID)
+	}))
+	defer server.Close()
+
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig
+	client, err := DialHTTP(server.URL, "",
+		WithTLSConfig(tlsConfig),
+		WithHeader("X-Custom", "a"),
+		WithBasicAuth("user", "pass"),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.SaveSession())
+
+	r := <-received
+	assert.Equal(t, []string{"a"}, r.Header["X-Custom"])
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+	username, password, ok := r.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+
+	// the TLS configuration can't be applied to an unknown transport
+	httpClient := &http.Client{Transport: struct{ http.RoundTripper }{http.DefaultTransport}}
+	_, err = DialHTTPWithClient(server.URL, "", httpClient, WithTLSConfig(tlsConfig))
+	assert.Equal(t, ErrTransportNotConfigurable, err)
+}
+
+func TestWithKeepAlive(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	release := make(chan struct{})
+	defer close(release)
+
+	// the server never reads, so it never answers pings
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			<-release
+			_ = ws.Close()
+		}
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client, err := Dial(url, "", WithKeepAlive(5*time.Millisecond, 20*time.Millisecond))
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.NoError(t, client.Err())
+
+	select {
+	case <-client.Done():
+		assert.Equal(t, ErrKeepAliveTimeout, client.Err())
+	case <-time.After(time.Second):
+		t.Fatal("dead connection wasn't detected")
+	}
+}
+
+func TestWithKeepAliveDefaultTimeout(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	release := make(chan struct{})
+
+	// the first server answers pings, the second one doesn't
+	answer := make(chan bool, 2)
+	answer <- true
+	answer <- false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		if <-answer {
+			// reading handles the pings
+			for {
+				if _, _, err := ws.NextReader(); err != nil {
+					return
+				}
+			}
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	alive, err := Dial(url, "", WithKeepAlive(5*time.Millisecond, 0))
+	require.NoError(t, err)
+	defer alive.Close()
+
+	select {
+	case <-alive.Done():
+		t.Fatalf("connection was closed: %v", alive.Err())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	dead, err := Dial(url, "", WithKeepAlive(5*time.Millisecond, 0))
+	require.NoError(t, err)
+	defer dead.Close()
+
+	select {
+	case <-dead.Done():
+		assert.Equal(t, ErrKeepAliveTimeout, dead.Err())
+	case <-time.After(time.Second):
+		t.Fatal("dead connection wasn't detected")
+	}
+}
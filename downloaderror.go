@@ -0,0 +1,82 @@
+package arigo
+
+import (
+	"context"
+	"fmt"
+)
+
+// DownloadError is the error of a download which failed.
+// It matches ErrDownloadError using errors.Is.
+type DownloadError struct {
+	GID          string
+	ExitStatus   ExitStatus // Code of the last error of the download
+	ErrorMessage string     // Human readable message associated with ExitStatus
+	Files        []string   // Paths of the selected files which weren't completed
+}
+
+// newDownloadError creates the error for the failed download.
+// Only the fields of status which were requested are used.
+func newDownloadError(status Status) *DownloadError {
+	err := &DownloadError{
+		GID:          status.GID,
+		ExitStatus:   status.ErrorCode,
+		ErrorMessage: status.ErrorMessage,
+	}
+
+	for _, file := range status.Files {
+		if file.Selected && file.Path != "" && file.CompletedLength < file.Length {
+			err.Files = append(err.Files, file.Path)
+		}
+	}
+
+	return err
+}
+
+func (e *DownloadError) Error() string {
+	if e.ErrorMessage == "" {
+		return fmt.Sprintf("download %s encountered error: %s", e.GID, e.ExitStatus)
+	}
+	return fmt.Sprintf("download %s encountered error: %s (%s)", e.GID, e.ErrorMessage, e.ExitStatus)
+}
+
+// Is reports whether target is ErrDownloadError.
+func (e *DownloadError) Is(target error) bool {
+	return target == ErrDownloadError
+}
+
+// IsNetwork reports whether the download failed because of a network problem.
+func (e *DownloadError) IsNetwork() bool {
+	switch e.ExitStatus {
+	case Timeout, DownloadSpeedTooSlow, NetworkError, NameResolutionFailed,
+		FTPCommandFailed, HTTPResponseHeaderBad:
+		return true
+	}
+	return false
+}
+
+// IsDiskFull reports whether the download failed because there wasn't enough disk space.
+func (e *DownloadError) IsDiskFull() bool {
+	return e.ExitStatus == NotEnoughDiskSpace
+}
+
+// IsRetryable reports whether the download might succeed if it's retried as is.
+// This is the case for network problems, overloaded servers and corrupted data,
+// but not for things like missing resources or invalid input.
+func (e *DownloadError) IsRetryable() bool {
+	switch e.ExitStatus {
+	case RemoteServerHandleRequestError, ChecksumValidationFailed:
+		return true
+	}
+	return e.IsNetwork()
+}
+
+// downloadError requests the status of the failed download and returns its error.
+// If the status can't be requested, the error only contains the GID.
+func (c *Client) downloadError(ctx context.Context, gid string) error {
+	status, err := c.TellStatusContext(ctx, gid, resultKeys...)
+	if err != nil {
+		return &DownloadError{GID: gid}
+	}
+
+	return newDownloadError(status)
+}
@@ -0,0 +1,56 @@
+package arigo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadError(t *testing.T) {
+	err := newDownloadError(Status{
+		GID:          "2089b05ecca3d829",
+		Status:       StatusError,
+		ErrorCode:    NotEnoughDiskSpace,
+		ErrorMessage: "No space left on device",
+		Files: []File{
+			{Path: "/downloads/a", Length: 10, CompletedLength: 10, Selected: true},
+			{Path: "/downloads/b", Length: 10, CompletedLength: 5, Selected: true},
+			{Path: "/downloads/c", Length: 10, Selected: false},
+		},
+	})
+
+	assert.Equal(t, []string{"/downloads/b"}, err.Files)
+	assert.Equal(t, "download 2089b05ecca3d829 encountered error: No space left on device (NotEnoughDiskSpace)", err.Error())
+
+	wrapped := fmt.Errorf("downloading: %w", err)
+	assert.True(t, errors.Is(wrapped, ErrDownloadError))
+	assert.False(t, errors.Is(wrapped, ErrDownloadStopped))
+
+	assert.True(t, err.IsDiskFull())
+	assert.False(t, err.IsNetwork())
+	assert.False(t, err.IsRetryable())
+}
+
+func TestDownloadErrorClassification(t *testing.T) {
+	tests := []struct {
+		status    ExitStatus
+		network   bool
+		retryable bool
+	}{
+		{NetworkError, true, true},
+		{Timeout, true, true},
+		{NameResolutionFailed, true, true},
+		{RemoteServerHandleRequestError, false, true},
+		{ChecksumValidationFailed, false, true},
+		{ResourceNotFound, false, false},
+		{HTTPAuthorizationFailed, false, false},
+	}
+
+	for _, test := range tests {
+		err := &DownloadError{ExitStatus: test.status}
+		assert.Equal(t, test.network, err.IsNetwork(), test.status.String())
+		assert.Equal(t, test.retryable, err.IsRetryable(), test.status.String())
+	}
+}
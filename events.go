@@ -51,32 +51,87 @@ type EventDispatcher interface {
 	Dispatch(evtType EventType, event *DownloadEvent)
 }
 
-type listenerData struct {
-	f  EventListener
-	id uint64
+// subscription calls its listener with the events dispatched to it.
+// Events are queued and handled one after another by a goroutine
+// which only runs while there are queued events.
+type subscription struct {
+	f EventListener
+
+	mut     sync.Mutex
+	queue   []*DownloadEvent
+	running bool
+	removed bool
 }
 
+func newSubscription(f EventListener) *subscription {
+	return &subscription{f: f}
+}
+
+// enqueue queues the event, it never blocks.
+func (l *subscription) enqueue(event *DownloadEvent) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.removed {
+		return
+	}
+
+	l.queue = append(l.queue, event)
+	if !l.running {
+		l.running = true
+		go l.run()
+	}
+}
+
+// run handles the queued events until there are none left.
+func (l *subscription) run() {
+	for {
+		l.mut.Lock()
+		if l.removed || len(l.queue) == 0 {
+			l.running = false
+			l.mut.Unlock()
+			return
+		}
+
+		event := l.queue[0]
+		l.queue[0] = nil
+		l.queue = l.queue[1:]
+		l.mut.Unlock()
+
+		l.f(event)
+	}
+}
+
+// remove discards the queued events and prevents new ones from being queued.
+func (l *subscription) remove() {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.removed = true
+	l.queue = nil
+}
+
+// eventTarget dispatches events to listeners without ever blocking.
+// Every listener receives the events in the order they were dispatched in
+// and is never called concurrently.
+// Listeners may subscribe and unsubscribe, even from inside a listener.
 type eventTarget struct {
-	listenerMap map[EventType][]listenerData
-	currentID   uint64
-	mut         sync.RWMutex
+	listenerMap map[EventType][]*subscription
+	mut         sync.Mutex
 }
 
-func (t *eventTarget) unsubscribe(evtType EventType, id uint64) bool {
+func (t *eventTarget) unsubscribe(evtType EventType, l *subscription) bool {
 	t.mut.Lock()
 	defer t.mut.Unlock()
 
 	listeners := t.listenerMap[evtType]
-	if len(listeners) == 0 {
-		return false
-	}
 
 	found := false
-	for i, l := range listeners {
-		if l.id == id {
+	for i, other := range listeners {
+		if other == l {
 			found = true
-			listeners[i] = listeners[len(listeners)-1]
-			listeners = listeners[:len(listeners)-1]
+			// copy so that a concurrent Dispatch isn't affected
+			listeners = append(listeners[:i:i], listeners[i+1:]...)
 			break
 		}
 	}
@@ -91,42 +146,38 @@ func (t *eventTarget) unsubscribe(evtType EventType, id uint64) bool {
 		delete(t.listenerMap, evtType)
 	}
 
+	l.remove()
 	return true
 }
 
+// Subscribe registers the listener for the event.
+// The returned function unsubscribes the listener, after which it's no longer called,
+// except for a call which is already in progress.
 func (t *eventTarget) Subscribe(evtType EventType, listener EventListener) UnsubscribeFunc {
+	l := newSubscription(listener)
+
 	t.mut.Lock()
 	defer t.mut.Unlock()
 
-	id := t.currentID
-	t.currentID++
-
 	if t.listenerMap == nil {
-		t.listenerMap = make(map[EventType][]listenerData)
+		t.listenerMap = make(map[EventType][]*subscription)
 	}
 
-	t.listenerMap[evtType] = append(t.listenerMap[evtType], listenerData{id: id, f: listener})
+	t.listenerMap[evtType] = append(t.listenerMap[evtType], l)
 
 	return func() bool {
-		return t.unsubscribe(evtType, id)
+		return t.unsubscribe(evtType, l)
 	}
 }
 
+// Dispatch queues the event for all listeners subscribed to evtType.
+// It returns without waiting for the listeners.
 func (t *eventTarget) Dispatch(evtType EventType, event *DownloadEvent) {
-	t.mut.RLock()
-	defer t.mut.RUnlock()
-
-	var wg sync.WaitGroup
-
+	t.mut.Lock()
 	listeners := t.listenerMap[evtType]
+	t.mut.Unlock()
 
-	wg.Add(len(listeners))
-	for _, listener := range listeners {
-		go func(l listenerData) {
-			l.f(event)
-			wg.Done()
-		}(listener)
+	for _, l := range listeners {
+		l.enqueue(event)
 	}
-
-	wg.Wait()
 }
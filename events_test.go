@@ -1,34 +1,206 @@
 package arigo
 
 import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"testing"
 )
 
 func TestEventTarget(t *testing.T) {
 	var evtTarget eventTarget
 
-	var events []*DownloadEvent
+	events := make(chan *DownloadEvent, 10)
 
 	unsub := evtTarget.Subscribe(StartEvent, func(event *DownloadEvent) {
-		events = append(events, event)
+		events <- event
 	})
 
 	evtTarget.Dispatch(StartEvent, &DownloadEvent{"1"})
 	evtTarget.Dispatch(CompleteEvent, &DownloadEvent{"2"})
 	evtTarget.Dispatch(StartEvent, &DownloadEvent{"3"})
 
-	unsub()
-	unsub()
+	assert.Equal(t, "1", (<-events).GID)
+	assert.Equal(t, "3", (<-events).GID)
+
+	assert.True(t, unsub())
+	assert.False(t, unsub())
 
 	evtTarget.Dispatch(StartEvent, &DownloadEvent{"4"})
 
-	require.Len(t, events, 2, "should only receive two events")
+	select {
+	case event := <-events:
+		t.Fatalf("received event %s after unsubscribing", event)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestEventTargetUnsubscribeInListener(t *testing.T) {
+	var evtTarget eventTarget
+
+	done := make(chan struct{})
+
+	var unsub UnsubscribeFunc
+	unsub = evtTarget.Subscribe(CompleteEvent, func(event *DownloadEvent) {
+		unsub()
+
+		// subscribing from inside a listener mustn't block either
+		evtTarget.Subscribe(StopEvent, func(event *DownloadEvent) {})()
+
+		evtTarget.Dispatch(StopEvent, event)
+		close(done)
+	})
+
+	evtTarget.Dispatch(CompleteEvent, &DownloadEvent{"1"})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listener deadlocked")
+	}
+}
+
+func TestEventTargetBlockedListener(t *testing.T) {
+	var evtTarget eventTarget
+
+	block := make(chan struct{})
+	defer close(block)
+
+	evtTarget.Subscribe(StartEvent, func(event *DownloadEvent) {
+		<-block
+	})
+
+	received := make(chan struct{}, 10)
+	evtTarget.Subscribe(StartEvent, func(event *DownloadEvent) {
+		received <- struct{}{}
+	})
+
+	dispatched := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			evtTarget.Dispatch(StartEvent, &DownloadEvent{strconv.Itoa(i)})
+		}
+		close(dispatched)
+	}()
+
+	select {
+	case <-dispatched:
+	case <-time.After(time.Second):
+		t.Fatal("Dispatch blocked on a slow listener")
+	}
+
+	for i := 0; i < 10; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("a slow listener delayed the others")
+		}
+	}
+}
+
+// TestEventTargetStress subscribes, unsubscribes and dispatches concurrently,
+// it's meant to be run with the race detector.
+func TestEventTargetStress(t *testing.T) {
+	const (
+		numListeners = 20
+		numEvents    = 200
+	)
+
+	var evtTarget eventTarget
+
+	var wg sync.WaitGroup
+	wg.Add(numListeners)
+
+	for i := 0; i < numListeners; i++ {
+		next := 0
+		var unsub UnsubscribeFunc
+		unsub = evtTarget.Subscribe(StartEvent, func(event *DownloadEvent) {
+			// every listener receives the events in order
+			if assert.Equal(t, strconv.Itoa(next), event.GID) {
+				next++
+			}
+
+			if next == numEvents {
+				assert.True(t, unsub())
+				wg.Done()
+			}
+		})
+	}
+
+	// listeners which come and go while events are dispatched
+	stop := make(chan struct{})
+	var churn sync.WaitGroup
+	churn.Add(4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer churn.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				var unsub UnsubscribeFunc
+				unsub = evtTarget.Subscribe(StartEvent, func(event *DownloadEvent) {
+					unsub()
+					evtTarget.Subscribe(StopEvent, func(event *DownloadEvent) {})()
+				})
+				evtTarget.Dispatch(StopEvent, &DownloadEvent{"stop"})
+			}
+		}()
+	}
+
+	for i := 0; i < numEvents; i++ {
+		evtTarget.Dispatch(StartEvent, &DownloadEvent{strconv.Itoa(i)})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("listeners didn't receive all events")
+	}
+
+	close(stop)
+	churn.Wait()
+}
+
+func TestClientSubscribeOrder(t *testing.T) {
+	const numEvents = 100
+
+	client, ws, server := newTestClient(t)
+	defer server.Close()
+	defer client.Close()
+	defer ws.Close()
+
+	received := make(chan string, numEvents)
+	client.Subscribe(CompleteEvent, func(event *DownloadEvent) {
+		received <- event.GID
+	})
 
-	first := events[0].GID
-	assert.Equal(t, "1", first)
+	for i := 0; i < numEvents; i++ {
+		require.NoError(t, ws.WriteJSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "aria2.onDownloadComplete",
+			"params":  []interface{}{map[string]string{"gid": strconv.Itoa(i)}},
+		}))
+	}
 
-	second := events[1].GID
-	assert.Equal(t, "3", second)
+	for i := 0; i < numEvents; i++ {
+		select {
+		case gid := <-received:
+			require.Equal(t, strconv.Itoa(i), gid)
+		case <-time.After(time.Second):
+			t.Fatal("didn't receive event")
+		}
+	}
 }
@@ -0,0 +1,398 @@
+package arigo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/rpc2"
+	"github.com/siku2/arigo/pkg/aria2proto"
+)
+
+// DefaultEventBufferSize is the capacity of the channel of an EventStream
+// if EventFilter.BufferSize is not set.
+const DefaultEventBufferSize = 64
+
+// ErrEventOverflow is reported by EventStream.Err when the stream was closed
+// because its consumer didn't keep up. See OverflowError.
+var ErrEventOverflow = errors.New("event stream overflow")
+
+// Event is an event received from aria2.
+type Event struct {
+	Type     EventType // Type of the event
+	GID      string    // GID of the download the event concerns
+	Received time.Time // Time the event was received
+}
+
+// OverflowPolicy determines what happens to the events of an EventStream
+// whose channel is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock waits until there's room in the channel.
+	// Events for all other streams of the client are delayed in the meantime,
+	// but calls aren't affected.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest event in the channel to make room.
+	OverflowDropOldest
+	// OverflowError closes the stream and reports ErrEventOverflow.
+	OverflowError
+)
+
+// EventFilter selects the events of an EventStream and configures
+// how they're delivered.
+type EventFilter struct {
+	// Types of the events to receive. If empty, events of all types are received.
+	Types []EventType
+	// GIDs of the downloads to receive the events of.
+	// If empty, the events of all downloads are received.
+	GIDs []string
+
+	// BufferSize is the capacity of the channel.
+	// If zero, DefaultEventBufferSize is used.
+	BufferSize int
+	// Overflow determines what happens when the channel is full.
+	Overflow OverflowPolicy
+}
+
+// EventStream delivers events received from aria2 over a channel.
+type EventStream struct {
+	// C delivers the events in the order they were received.
+	// It's closed when the stream ends, the reason is reported by Err.
+	C <-chan Event
+
+	c        chan Event
+	types    map[EventType]bool
+	gids     map[string]bool
+	overflow OverflowPolicy
+	bus      *eventBus
+
+	done      chan struct{}
+	err       error
+	closeOnce sync.Once
+
+	mut    sync.Mutex // held while sending to c
+	closed bool
+}
+
+func newEventStream(filter EventFilter, bus *eventBus) *EventStream {
+	size := filter.BufferSize
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+
+	c := make(chan Event, size)
+	s := &EventStream{
+		C:        c,
+		c:        c,
+		overflow: filter.Overflow,
+		bus:      bus,
+		done:     make(chan struct{}),
+	}
+
+	if len(filter.Types) > 0 {
+		s.types = make(map[EventType]bool, len(filter.Types))
+		for _, evtType := range filter.Types {
+			s.types[evtType] = true
+		}
+	}
+
+	if len(filter.GIDs) > 0 {
+		s.gids = make(map[string]bool, len(filter.GIDs))
+		for _, gid := range filter.GIDs {
+			s.gids[gid] = true
+		}
+	}
+
+	return s
+}
+
+// Err returns nil while the stream is open.
+// Afterwards it returns why it ended: the error of the context passed to
+// Client.Events, ErrEventOverflow or the error reported by Client.Err
+// if the client stopped working.
+func (s *EventStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *EventStream) matches(evt Event) bool {
+	if s.types != nil && !s.types[evt.Type] {
+		return false
+	}
+	if s.gids != nil && !s.gids[evt.GID] {
+		return false
+	}
+	return true
+}
+
+// deliver sends the event to the stream according to its overflow policy.
+func (s *EventStream) deliver(evt Event) {
+	if !s.matches(evt) {
+		return
+	}
+
+	s.mut.Lock()
+	if s.closed {
+		s.mut.Unlock()
+		return
+	}
+
+	overflowed := false
+
+	switch s.overflow {
+	case OverflowDropOldest:
+		for sent := false; !sent; {
+			select {
+			case s.c <- evt:
+				sent = true
+			default:
+				select {
+				case <-s.c:
+				default:
+				}
+			}
+		}
+	case OverflowError:
+		select {
+		case s.c <- evt:
+		default:
+			overflowed = true
+		}
+	default:
+		select {
+		case s.c <- evt:
+		case <-s.done:
+		}
+	}
+
+	s.mut.Unlock()
+
+	if overflowed {
+		s.bus.remove(s)
+		s.close(ErrEventOverflow)
+	}
+}
+
+// close ends the stream, only the first call has an effect.
+func (s *EventStream) close(err error) {
+	s.closeOnce.Do(func() {
+		s.err = err
+		// unblocks a pending send
+		close(s.done)
+
+		s.mut.Lock()
+		s.closed = true
+		close(s.c)
+		s.mut.Unlock()
+	})
+}
+
+// eventBus delivers events to event streams.
+// Events are queued and delivered by a separate goroutine,
+// so publishing never blocks.
+type eventBus struct {
+	mut      sync.Mutex
+	cond     *sync.Cond
+	queue    []Event
+	streams  map[*EventStream]struct{}
+	closed   bool
+	closeErr error
+
+	// ordered is true if events are dispatched by a notificationCodec
+	// in the order they're received.
+	ordered bool
+
+	// target receives the dispatched events as well.
+	// It's set before the client starts running.
+	target *eventTarget
+}
+
+func newEventBus(ordered bool) *eventBus {
+	b := &eventBus{
+		streams: make(map[*EventStream]struct{}),
+		ordered: ordered,
+	}
+	b.cond = sync.NewCond(&b.mut)
+
+	go b.run()
+
+	return b
+}
+
+// publish queues the event for delivery.
+func (b *eventBus) publish(evt Event) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.closed || len(b.streams) == 0 {
+		return
+	}
+
+	b.queue = append(b.queue, evt)
+	b.cond.Signal()
+}
+
+// dispatch publishes the event to the streams and dispatches it to the listeners of target.
+// It never blocks.
+func (b *eventBus) dispatch(evtType EventType, event *DownloadEvent) {
+	b.publish(Event{Type: evtType, GID: event.GID, Received: time.Now()})
+
+	if b.target != nil {
+		b.target.Dispatch(evtType, event)
+	}
+}
+
+// run delivers the queued events until the bus is closed.
+func (b *eventBus) run() {
+	for {
+		b.mut.Lock()
+		for len(b.queue) == 0 && !b.closed {
+			b.cond.Wait()
+		}
+
+		if b.closed {
+			b.mut.Unlock()
+			return
+		}
+
+		evt := b.queue[0]
+		b.queue[0] = Event{}
+		b.queue = b.queue[1:]
+
+		streams := make([]*EventStream, 0, len(b.streams))
+		for s := range b.streams {
+			streams = append(streams, s)
+		}
+		b.mut.Unlock()
+
+		for _, s := range streams {
+			s.deliver(evt)
+		}
+	}
+}
+
+// subscribe creates a new stream which ends when ctx is done.
+func (b *eventBus) subscribe(ctx context.Context, filter EventFilter) *EventStream {
+	s := newEventStream(filter, b)
+
+	b.mut.Lock()
+	if b.closed {
+		b.mut.Unlock()
+		s.close(b.closeErr)
+		return s
+	}
+	b.streams[s] = struct{}{}
+	b.mut.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.remove(s)
+			s.close(ctx.Err())
+		case <-s.done:
+		}
+	}()
+
+	return s
+}
+
+func (b *eventBus) remove(s *EventStream) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	delete(b.streams, s)
+}
+
+// close stops the delivery of events and ends all streams with err.
+func (b *eventBus) close(err error) {
+	b.mut.Lock()
+	if b.closed {
+		b.mut.Unlock()
+		return
+	}
+
+	b.closed = true
+	b.closeErr = err
+	b.queue = nil
+
+	streams := b.streams
+	b.streams = nil
+	b.cond.Broadcast()
+	b.mut.Unlock()
+
+	for s := range streams {
+		s.close(err)
+	}
+}
+
+var notificationEventTypes = map[string]EventType{
+	aria2proto.OnDownloadStart:      StartEvent,
+	aria2proto.OnDownloadPause:      PauseEvent,
+	aria2proto.OnDownloadStop:       StopEvent,
+	aria2proto.OnDownloadComplete:   CompleteEvent,
+	aria2proto.OnDownloadError:      ErrorEvent,
+	aria2proto.OnBTDownloadComplete: BTCompleteEvent,
+}
+
+// notificationCodec dispatches the notifications read by the wrapped codec
+// using an eventBus.
+// This happens inside the read loop of the rpc2 client, which handles
+// every notification in a separate goroutine, so it's the only place
+// where the order they arrive in is known.
+type notificationCodec struct {
+	rpc2.Codec
+	events *eventBus
+	method string
+}
+
+func newNotificationCodec(codec rpc2.Codec, events *eventBus) *notificationCodec {
+	return &notificationCodec{Codec: codec, events: events}
+}
+
+func (c *notificationCodec) ReadHeader(req *rpc2.Request, resp *rpc2.Response) error {
+	err := c.Codec.ReadHeader(req, resp)
+	c.method = req.Method
+	return err
+}
+
+func (c *notificationCodec) ReadRequestBody(body interface{}) error {
+	if err := c.Codec.ReadRequestBody(body); err != nil {
+		return err
+	}
+
+	evtType, ok := notificationEventTypes[c.method]
+	if !ok {
+		return nil
+	}
+
+	if event, ok := body.(*DownloadEvent); ok {
+		c.events.dispatch(evtType, event)
+	}
+
+	return nil
+}
+
+// Events returns a stream of the events received from aria2 which match filter.
+// Only events which are received after the call are delivered.
+// The stream ends when ctx is done or the client stops working.
+//
+// Clients which don't receive events, like the ones created by DialHTTP,
+// never deliver anything.
+func (c *Client) Events(ctx context.Context, filter EventFilter) *EventStream {
+	return c.events.subscribe(ctx, filter)
+}
+
+// handleEvent dispatches an event received by one of the notification handlers.
+func (c *Client) handleEvent(evtType EventType, event *DownloadEvent) {
+	// events which were already dispatched by a notificationCodec
+	// are ignored, the order is lost at this point
+	if !c.events.ordered {
+		c.events.dispatch(evtType, event)
+	}
+}
@@ -0,0 +1,134 @@
+package arigo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient creates a client connected to a WebSocket server
+// and returns it together with the server side of the connection.
+// The caller should close the server when finished.
+func newTestClient(t *testing.T) (*Client, *websocket.Conn, *httptest.Server) {
+	upgrader := websocket.Upgrader{}
+	conns := make(chan *websocket.Conn, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		conns <- ws
+	}))
+
+	client, err := Dial("ws"+strings.TrimPrefix(server.URL, "http"), "")
+	require.NoError(t, err)
+
+	return client, <-conns, server
+}
+
+func receiveEvent(t *testing.T, stream *EventStream) Event {
+	select {
+	case evt, ok := <-stream.C:
+		require.True(t, ok, "stream ended: %v", stream.Err())
+		return evt
+	case <-time.After(time.Second):
+		t.Fatal("didn't receive event")
+		return Event{}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := newEventBus(true)
+	defer bus.close(ErrClientClosed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := bus.subscribe(ctx, EventFilter{
+		Types: []EventType{StartEvent, CompleteEvent},
+		GIDs:  []string{"a", "b"},
+	})
+
+	bus.publish(Event{Type: StartEvent, GID: "a"})
+	bus.publish(Event{Type: StartEvent, GID: "c"})
+	bus.publish(Event{Type: PauseEvent, GID: "b"})
+	bus.publish(Event{Type: StartEvent, GID: "b"})
+	bus.publish(Event{Type: CompleteEvent, GID: "a"})
+
+	assert.Equal(t, Event{Type: StartEvent, GID: "a"}, receiveEvent(t, stream))
+	assert.Equal(t, Event{Type: StartEvent, GID: "b"}, receiveEvent(t, stream))
+	assert.Equal(t, Event{Type: CompleteEvent, GID: "a"}, receiveEvent(t, stream))
+
+	cancel()
+	_, ok := <-stream.C
+	assert.False(t, ok)
+	assert.Equal(t, context.Canceled, stream.Err())
+
+	stream = bus.subscribe(context.Background(), EventFilter{})
+	bus.close(ErrConnectionLost)
+	_, ok = <-stream.C
+	assert.False(t, ok)
+	assert.Equal(t, ErrConnectionLost, stream.Err())
+}
+
+func TestEventStreamOverflow(t *testing.T) {
+	bus := newEventBus(true)
+	defer bus.close(ErrClientClosed)
+
+	stream := newEventStream(EventFilter{BufferSize: 2, Overflow: OverflowDropOldest}, bus)
+	for i := 0; i < 4; i++ {
+		stream.deliver(Event{GID: strconv.Itoa(i)})
+	}
+
+	assert.Equal(t, "2", (<-stream.C).GID)
+	assert.Equal(t, "3", (<-stream.C).GID)
+	assert.NoError(t, stream.Err())
+
+	stream = newEventStream(EventFilter{BufferSize: 1, Overflow: OverflowError}, bus)
+	stream.deliver(Event{GID: "0"})
+	assert.NoError(t, stream.Err())
+	stream.deliver(Event{GID: "1"})
+	assert.Equal(t, ErrEventOverflow, stream.Err())
+
+	assert.Equal(t, "0", (<-stream.C).GID)
+	_, ok := <-stream.C
+	assert.False(t, ok)
+}
+
+func TestClientEvents(t *testing.T) {
+	const numEvents = 100
+
+	client, ws, server := newTestClient(t)
+	defer server.Close()
+	defer client.Close()
+	defer ws.Close()
+
+	stream := client.Events(context.Background(), EventFilter{BufferSize: numEvents})
+
+	for i := 0; i < numEvents; i++ {
+		require.NoError(t, ws.WriteJSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "aria2.onDownloadStart",
+			"params":  []interface{}{map[string]string{"gid": strconv.Itoa(i)}},
+		}))
+	}
+
+	var last time.Time
+	for i := 0; i < numEvents; i++ {
+		evt := receiveEvent(t, stream)
+		assert.Equal(t, StartEvent, evt.Type)
+		assert.Equal(t, strconv.Itoa(i), evt.GID)
+		assert.False(t, evt.Received.Before(last))
+		last = evt.Received
+	}
+
+	require.NoError(t, client.Close())
+	_, ok := <-stream.C
+	assert.False(t, ok)
+	assert.Equal(t, ErrClientClosed, stream.Err())
+}
@@ -1,5 +1,7 @@
 package arigo
 
+import "context"
+
 // GID provides an object oriented approach to arigo.
 // Instead of calling the methods on the client directly,
 // you can call them on the GID instance.
@@ -24,19 +26,47 @@ func (gid *GID) Subscribe(evtType EventType, listener EventListener) Unsubscribe
 
 // Delete removes the download from disk as well as from aria2.
 func (gid *GID) Delete() error {
-	return gid.client.Delete(gid.GID)
+	return gid.DeleteContext(context.Background())
+}
+
+// DeleteContext is like Delete but the call is canceled when ctx is done.
+func (gid *GID) DeleteContext(ctx context.Context) error {
+	return gid.client.DeleteContext(ctx, gid.GID)
 }
 
 // WaitForDownload waits for the download to finish.
 func (gid *GID) WaitForDownload() error {
-	return gid.client.WaitForDownload(gid.GID)
+	return gid.WaitForDownloadContext(context.Background())
+}
+
+// WaitForDownloadContext waits for the download to finish or for ctx to be done,
+// in which case ctx.Err() is returned.
+func (gid *GID) WaitForDownloadContext(ctx context.Context) error {
+	return gid.client.WaitForDownloadContext(ctx, gid.GID)
+}
+
+// WaitForDownloadChain waits for the download and all downloads following it to finish.
+// See Client.WaitForDownloadChain.
+func (gid *GID) WaitForDownloadChain() ([]Status, error) {
+	return gid.WaitForDownloadChainContext(context.Background())
+}
+
+// WaitForDownloadChainContext is like WaitForDownloadChain but stops waiting
+// when ctx is done.
+func (gid *GID) WaitForDownloadChainContext(ctx context.Context) ([]Status, error) {
+	return gid.client.WaitForDownloadChainContext(ctx, gid.GID)
 }
 
 // Remove removes the download.
 // If the specified download is in progress, it is first stopped.
 // The status of the removed download becomes removed.
 func (gid *GID) Remove() error {
-	return gid.client.Remove(gid.GID)
+	return gid.RemoveContext(context.Background())
+}
+
+// RemoveContext is like Remove but the call is canceled when ctx is done.
+func (gid *GID) RemoveContext(ctx context.Context) error {
+	return gid.client.RemoveContext(ctx, gid.GID)
 }
 
 // ForceRemove removes the download.
@@ -44,7 +74,12 @@ func (gid *GID) Remove() error {
 // without performing any actions which take time, such as contacting BitTorrent trackers to
 // unregister the download first.
 func (gid *GID) ForceRemove() error {
-	return gid.client.ForceRemove(gid.GID)
+	return gid.ForceRemoveContext(context.Background())
+}
+
+// ForceRemoveContext is like ForceRemove but the call is canceled when ctx is done.
+func (gid *GID) ForceRemoveContext(ctx context.Context) error {
+	return gid.client.ForceRemoveContext(ctx, gid.GID)
 }
 
 // Pause pauses the download.
@@ -52,7 +87,12 @@ func (gid *GID) ForceRemove() error {
 // the download is placed in the front of the queue. While the status is paused,
 // the download is not started. To change status to waiting, use the Unpause() method.
 func (gid *GID) Pause() error {
-	return gid.client.Pause(gid.GID)
+	return gid.PauseContext(context.Background())
+}
+
+// PauseContext is like Pause but the call is canceled when ctx is done.
+func (gid *GID) PauseContext(ctx context.Context) error {
+	return gid.client.PauseContext(ctx, gid.GID)
 }
 
 // ForcePause pauses the download.
@@ -60,13 +100,23 @@ func (gid *GID) Pause() error {
 // without performing any actions which take time, such as contacting BitTorrent trackers to
 // unregister the download first.
 func (gid *GID) ForcePause() error {
-	return gid.client.ForcePause(gid.GID)
+	return gid.ForcePauseContext(context.Background())
+}
+
+// ForcePauseContext is like ForcePause but the call is canceled when ctx is done.
+func (gid *GID) ForcePauseContext(ctx context.Context) error {
+	return gid.client.ForcePauseContext(ctx, gid.GID)
 }
 
 // Unpause changes the status of the download from paused to waiting,
 // making the download eligible to be restarted.
 func (gid *GID) Unpause() error {
-	return gid.client.Unpause(gid.GID)
+	return gid.UnpauseContext(context.Background())
+}
+
+// UnpauseContext is like Unpause but the call is canceled when ctx is done.
+func (gid *GID) UnpauseContext(ctx context.Context) error {
+	return gid.client.UnpauseContext(ctx, gid.GID)
 }
 
 // TellStatus returns the progress of the download.
@@ -74,32 +124,57 @@ func (gid *GID) Unpause() error {
 // If keys is empty, the response contains all keys.
 // This is useful when you just want specific keys and avoid unnecessary transfers.
 func (gid *GID) TellStatus(keys ...string) (Status, error) {
-	return gid.client.TellStatus(gid.GID, keys...)
+	return gid.TellStatusContext(context.Background(), keys...)
+}
+
+// TellStatusContext is like TellStatus but the call is canceled when ctx is done.
+func (gid *GID) TellStatusContext(ctx context.Context, keys ...string) (Status, error) {
+	return gid.client.TellStatusContext(ctx, gid.GID, keys...)
 }
 
 // GetURIs returns the URIs used in the download.
 // The response is a slice of URI.
 func (gid *GID) GetURIs() ([]URI, error) {
-	return gid.client.GetURIs(gid.GID)
+	return gid.GetURIsContext(context.Background())
+}
+
+// GetURIsContext is like GetURIs but the call is canceled when ctx is done.
+func (gid *GID) GetURIsContext(ctx context.Context) ([]URI, error) {
+	return gid.client.GetURIsContext(ctx, gid.GID)
 }
 
 // GetFiles returns the file list of the download.
 // The response is a slice of File.
 func (gid *GID) GetFiles() ([]File, error) {
-	return gid.client.GetFiles(gid.GID)
+	return gid.GetFilesContext(context.Background())
+}
+
+// GetFilesContext is like GetFiles but the call is canceled when ctx is done.
+func (gid *GID) GetFilesContext(ctx context.Context) ([]File, error) {
+	return gid.client.GetFilesContext(ctx, gid.GID)
 }
 
 // GetPeers returns a list of peers of the download denoted by gid.
 // This method is for BitTorrent only.
 // The response is a slice of Peers.
 func (gid *GID) GetPeers() ([]Peer, error) {
-	return gid.client.GetPeers(gid.GID)
+	return gid.GetPeersContext(context.Background())
+}
+
+// GetPeersContext is like GetPeers but the call is canceled when ctx is done.
+func (gid *GID) GetPeersContext(ctx context.Context) ([]Peer, error) {
+	return gid.client.GetPeersContext(ctx, gid.GID)
 }
 
 // GetServers returns currently connected HTTP(S)/FTP/SFTP servers of the download denoted by gid.
 // Returns a slice of FileServers.
 func (gid *GID) GetServers() ([]FileServers, error) {
-	return gid.client.GetServers(gid.GID)
+	return gid.GetServersContext(context.Background())
+}
+
+// GetServersContext is like GetServers but the call is canceled when ctx is done.
+func (gid *GID) GetServersContext(ctx context.Context) ([]FileServers, error) {
+	return gid.client.GetServersContext(ctx, gid.GID)
 }
 
 // ChangePosition changes the position of the download denoted by gid in the queue.
@@ -112,7 +187,12 @@ func (gid *GID) GetServers() ([]FileServers, error) {
 //
 // The response is an integer denoting the resulting position.
 func (gid *GID) ChangePosition(pos int, how PositionSetBehaviour) (int, error) {
-	return gid.client.ChangePosition(gid.GID, pos, how)
+	return gid.ChangePositionContext(context.Background(), pos, how)
+}
+
+// ChangePositionContext is like ChangePosition but the call is canceled when ctx is done.
+func (gid *GID) ChangePositionContext(ctx context.Context, pos int, how PositionSetBehaviour) (int, error) {
+	return gid.client.ChangePositionContext(ctx, gid.GID, pos, how)
 }
 
 // ChangeURIAt removes the URIs in delUris from and appends the URIs in addUris to download denoted by gid.
@@ -129,7 +209,12 @@ func (gid *GID) ChangePosition(pos int, how PositionSetBehaviour) (int, error) {
 // The first integer is the number of URIs deleted.
 // The second integer is the number of URIs added.
 func (gid *GID) ChangeURIAt(fileIndex uint, delURIs []string, addURIs []string, position uint) (uint, uint, error) {
-	return gid.client.ChangeURIAt(gid.GID, fileIndex, delURIs, addURIs, position)
+	return gid.ChangeURIAtContext(context.Background(), fileIndex, delURIs, addURIs, position)
+}
+
+// ChangeURIAtContext is like ChangeURIAt but the call is canceled when ctx is done.
+func (gid *GID) ChangeURIAtContext(ctx context.Context, fileIndex uint, delURIs []string, addURIs []string, position uint) (uint, uint, error) {
+	return gid.client.ChangeURIAtContext(ctx, gid.GID, fileIndex, delURIs, addURIs, position)
 }
 
 // ChangeURI removes the URIs in delUris from and appends the URIs in addUris to download denoted by gid.
@@ -146,17 +231,40 @@ func (gid *GID) ChangeURIAt(fileIndex uint, delURIs []string, addURIs []string,
 // The first integer is the number of URIs deleted.
 // The second integer is the number of URIs added.
 func (gid *GID) ChangeURI(fileIndex uint, delURIs []string, addURIs []string) (uint, uint, error) {
-	return gid.client.ChangeURI(gid.GID, fileIndex, delURIs, addURIs)
+	return gid.ChangeURIContext(context.Background(), fileIndex, delURIs, addURIs)
+}
+
+// ChangeURIContext is like ChangeURI but the call is canceled when ctx is done.
+func (gid *GID) ChangeURIContext(ctx context.Context, fileIndex uint, delURIs []string, addURIs []string) (uint, uint, error) {
+	return gid.client.ChangeURIContext(ctx, gid.GID, fileIndex, delURIs, addURIs)
 }
 
 // GetOptions returns Options of the download denoted by gid.
 // Note that this method does not return options which have no default value and have not been set on the command-line,
 // in configuration files or RPC methods.
 func (gid *GID) GetOptions() (Options, error) {
-	return gid.client.GetOptions(gid.GID)
+	return gid.GetOptionsContext(context.Background())
+}
+
+// GetOptionsContext is like GetOptions but the call is canceled when ctx is done.
+func (gid *GID) GetOptionsContext(ctx context.Context) (Options, error) {
+	return gid.client.GetOptionsContext(ctx, gid.GID)
+}
+
+// GetOptionValues is like GetOptions but returns the options as OptionValues,
+// which keeps the options which are false or zero.
+func (gid *GID) GetOptionValues() (OptionValues, error) {
+	return gid.GetOptionValuesContext(context.Background())
+}
+
+// GetOptionValuesContext is like GetOptionValues but the call is canceled when ctx is done.
+func (gid *GID) GetOptionValuesContext(ctx context.Context) (OptionValues, error) {
+	return gid.client.GetOptionValuesContext(ctx, gid.GID)
 }
 
 // ChangeOptions changes options of the download denoted by gid dynamically.
+// Only the options which are set are changed. Options which are false or zero
+// can only be set using OptionValues.
 //
 // Except for following options, all options are available:
 // 	- DryRun
@@ -174,11 +282,21 @@ func (gid *GID) GetOptions() (Options, error) {
 // 	- ForceSave
 // 	- MaxDownloadLimit
 // 	- MaxUploadLimit
-func (gid *GID) ChangeOptions(changes Options) error {
-	return gid.client.ChangeOptions(gid.GID, changes)
+func (gid *GID) ChangeOptions(changes OptionSet) error {
+	return gid.ChangeOptionsContext(context.Background(), changes)
+}
+
+// ChangeOptionsContext is like ChangeOptions but the call is canceled when ctx is done.
+func (gid *GID) ChangeOptionsContext(ctx context.Context, changes OptionSet) error {
+	return gid.client.ChangeOptionsContext(ctx, gid.GID, changes)
 }
 
 // RemoveDownloadResult removes a completed/error/removed download denoted by gid from memory.
 func (gid *GID) RemoveDownloadResult() error {
-	return gid.client.RemoveDownloadResult(gid.GID)
+	return gid.RemoveDownloadResultContext(context.Background())
+}
+
+// RemoveDownloadResultContext is like RemoveDownloadResult but the call is canceled when ctx is done.
+func (gid *GID) RemoveDownloadResultContext(ctx context.Context) error {
+	return gid.client.RemoveDownloadResultContext(ctx, gid.GID)
 }
@@ -0,0 +1,196 @@
+package arigo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrOptionNotChangeable is matched by the errors of ChangeGlobalOptions calls
+// which try to change an option aria2 doesn't allow to change at runtime.
+var ErrOptionNotChangeable = errors.New("option can't be changed at runtime")
+
+// OptionError is the error of a call which sets an option that can't be changed.
+// It matches ErrOptionNotChangeable using errors.Is.
+type OptionError struct {
+	Options []string // Names of the options, like "rpc-listen-port"
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("options can't be changed at runtime: %s", strings.Join(e.Options, ", "))
+}
+
+// Is reports whether target is ErrOptionNotChangeable.
+func (e *OptionError) Is(target error) bool {
+	return target == ErrOptionNotChangeable
+}
+
+// GlobalOptions represents the global options of aria2.
+// They consist of the Options, which are used as the defaults for new downloads,
+// and the options which only apply to aria2 as a whole.
+type GlobalOptions struct {
+	Options
+
+	AsyncDNSServer               string   `json:"async-dns-server,omitempty"`
+	AutoSaveInterval             uint     `json:"auto-save-interval,omitempty,string"`
+	BTDetachSeedOnly             bool     `json:"bt-detach-seed-only,omitempty,string"`
+	BTLpdInterface               string   `json:"bt-lpd-interface,omitempty"`
+	BTMaxOpenFiles               uint     `json:"bt-max-open-files,omitempty,string"`
+	CACertificate                string   `json:"ca-certificate,omitempty"`
+	Certificate                  string   `json:"certificate,omitempty"`
+	CheckCertificate             bool     `json:"check-certificate,omitempty,string"`
+	ConfPath                     string   `json:"conf-path,omitempty"`
+	ConsoleLogLevel              string   `json:"console-log-level,omitempty"`
+	Daemon                       bool     `json:"daemon,omitempty,string"`
+	DeferredInput                bool     `json:"deferred-input,omitempty,string"`
+	DHTEntryPoint                string   `json:"dht-entry-point,omitempty"`
+	DHTEntryPoint6               string   `json:"dht-entry-point6,omitempty"`
+	DHTFilePath                  string   `json:"dht-file-path,omitempty"`
+	DHTFilePath6                 string   `json:"dht-file-path6,omitempty"`
+	DHTListenAddr6               string   `json:"dht-listen-addr6,omitempty"`
+	DHTListenPort                string   `json:"dht-listen-port,omitempty"`
+	DHTMessageTimeout            uint     `json:"dht-message-timeout,omitempty,string"`
+	DisableIPv6                  bool     `json:"disable-ipv6,omitempty,string"`
+	DiskCache                    ByteSize `json:"disk-cache,omitempty"`
+	DownloadResult               string   `json:"download-result,omitempty"`
+	DSCP                         uint     `json:"dscp,omitempty,string"`
+	EnableAsyncDNS6              bool     `json:"enable-async-dns6,omitempty,string"`
+	EnableColor                  bool     `json:"enable-color,omitempty,string"`
+	EnableDHT                    bool     `json:"enable-dht,omitempty,string"`
+	EnableDHT6                   bool     `json:"enable-dht6,omitempty,string"`
+	EnableRPC                    bool     `json:"enable-rpc,omitempty,string"`
+	EventPoll                    string   `json:"event-poll,omitempty"`
+	HumanReadable                bool     `json:"human-readable,omitempty,string"`
+	InputFile                    string   `json:"input-file,omitempty"`
+	Interface                    string   `json:"interface,omitempty"`
+	KeepUnfinishedDownloadResult bool     `json:"keep-unfinished-download-result,omitempty,string"`
+	ListenPort                   string   `json:"listen-port,omitempty"`
+	LoadCookies                  string   `json:"load-cookies,omitempty"`
+	Log                          string   `json:"log,omitempty"`
+	LogLevel                     string   `json:"log-level,omitempty"`
+	MaxConcurrentDownloads       uint     `json:"max-concurrent-downloads,omitempty,string"`
+	MaxDownloadResult            uint     `json:"max-download-result,omitempty,string"`
+	MaxOverallDownloadLimit      Rate     `json:"max-overall-download-limit,omitempty"`
+	MaxOverallUploadLimit        Rate     `json:"max-overall-upload-limit,omitempty"`
+	MinTLSVersion                string   `json:"min-tls-version,omitempty"`
+	MultipleInterface            string   `json:"multiple-interface,omitempty"`
+	NetrcPath                    string   `json:"netrc-path,omitempty"`
+	OnBTDownloadComplete         string   `json:"on-bt-download-complete,omitempty"`
+	OnDownloadComplete           string   `json:"on-download-complete,omitempty"`
+	OnDownloadError              string   `json:"on-download-error,omitempty"`
+	OnDownloadPause              string   `json:"on-download-pause,omitempty"`
+	OnDownloadStart              string   `json:"on-download-start,omitempty"`
+	OnDownloadStop               string   `json:"on-download-stop,omitempty"`
+	OptimizeConcurrentDownloads  string   `json:"optimize-concurrent-downloads,omitempty"`
+	PeerAgent                    string   `json:"peer-agent,omitempty"`
+	PeerIDPrefix                 string   `json:"peer-id-prefix,omitempty"`
+	PrivateKey                   string   `json:"private-key,omitempty"`
+	Quiet                        bool     `json:"quiet,omitempty,string"`
+	RlimitNofile                 uint     `json:"rlimit-nofile,omitempty,string"`
+	RPCAllowOriginAll            bool     `json:"rpc-allow-origin-all,omitempty,string"`
+	RPCCertificate               string   `json:"rpc-certificate,omitempty"`
+	RPCListenAll                 bool     `json:"rpc-listen-all,omitempty,string"`
+	RPCListenPort                uint     `json:"rpc-listen-port,omitempty,string"`
+	RPCMaxRequestSize            ByteSize `json:"rpc-max-request-size,omitempty"`
+	RPCPrivateKey                string   `json:"rpc-private-key,omitempty"`
+	RPCSecret                    string   `json:"rpc-secret,omitempty"`
+	RPCSecure                    bool     `json:"rpc-secure,omitempty,string"`
+	SaveCookies                  string   `json:"save-cookies,omitempty"`
+	SaveNotFound                 bool     `json:"save-not-found,omitempty,string"`
+	SaveSession                  string   `json:"save-session,omitempty"`
+	SaveSessionInterval          uint     `json:"save-session-interval,omitempty,string"`
+	ServerStatIf                 string   `json:"server-stat-if,omitempty"`
+	ServerStatOf                 string   `json:"server-stat-of,omitempty"`
+	ServerStatTimeout            uint     `json:"server-stat-timeout,omitempty,string"`
+	ShowConsoleReadout           bool     `json:"show-console-readout,omitempty,string"`
+	SocketRecvBufferSize         ByteSize `json:"socket-recv-buffer-size,omitempty"`
+	Stop                         uint     `json:"stop,omitempty,string"`
+	StopWithProcess              uint     `json:"stop-with-process,omitempty,string"`
+	SummaryInterval              uint     `json:"summary-interval,omitempty,string"`
+	TruncateConsoleReadout       bool     `json:"truncate-console-readout,omitempty,string"`
+}
+
+// runtimeGlobalOptions are the global-only options which can be changed
+// using ChangeGlobalOptions.
+var runtimeGlobalOptions = map[string]bool{
+	"bt-max-open-files":               true,
+	"download-result":                 true,
+	"keep-unfinished-download-result": true,
+	"log":                             true,
+	"log-level":                       true,
+	"max-concurrent-downloads":        true,
+	"max-download-result":             true,
+	"max-overall-download-limit":      true,
+	"max-overall-upload-limit":        true,
+	"optimize-concurrent-downloads":   true,
+	"save-cookies":                    true,
+	"save-session":                    true,
+	"server-stat-of":                  true,
+}
+
+// perDownloadOnlyOptions are the download options which can't be changed globally.
+var perDownloadOnlyOptions = map[string]bool{
+	"checksum":    true,
+	"index-out":   true,
+	"out":         true,
+	"pause":       true,
+	"select-file": true,
+}
+
+// globalOnlyOptions are the names of the options of GlobalOptions
+// which aren't part of Options.
+var globalOnlyOptions = jsonFieldNames(reflect.TypeOf(GlobalOptions{}))
+
+// jsonFieldNames returns the JSON names of the fields of the struct type,
+// ignoring embedded structs.
+func jsonFieldNames(typ reflect.Type) map[string]bool {
+	names := make(map[string]bool, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		names[name] = true
+	}
+	return names
+}
+
+// changeableGlobal reports whether the option can be changed using ChangeGlobalOptions.
+func changeableGlobal(name string) bool {
+	return !perDownloadOnlyOptions[name] && (!globalOnlyOptions[name] || runtimeGlobalOptions[name])
+}
+
+// ChangeableGlobal returns the options which can be changed using ChangeGlobalOptions.
+// Use it to apply options which also contain ones that can only be set
+// when aria2 is started, for example those of a configuration file.
+func (values OptionValues) ChangeableGlobal() OptionValues {
+	changeable := OptionValues{}
+	for name, value := range values {
+		if changeableGlobal(name) {
+			changeable[name] = value
+		}
+	}
+	return changeable
+}
+
+// validateRuntime returns an *OptionError listing the options which are set
+// but can't be changed while aria2 is running.
+func validateRuntime(values OptionValues) error {
+	var invalid []string
+	for name := range values {
+		if !changeableGlobal(name) {
+			invalid = append(invalid, name)
+		}
+	}
+
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return &OptionError{Options: invalid}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package arigo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeGlobalOptions(t *testing.T) {
+	_, client := newTestClient(t)
+
+	err := client.ChangeGlobalOptions(arigo.GlobalOptions{
+		MaxConcurrentDownloads:  2,
+		MaxOverallDownloadLimit: 1048576,
+	})
+	require.NoError(t, err)
+
+	options, err := client.GetGlobalOptions()
+	require.NoError(t, err)
+	assert.Equal(t, "/downloads", options.Dir)
+	assert.Equal(t, uint(2), options.MaxConcurrentDownloads)
+	assert.Equal(t, arigo.Rate(1048576), options.MaxOverallDownloadLimit)
+
+	err = client.ChangeGlobalOptions(arigo.GlobalOptions{EnableRPC: true})
+	assert.True(t, errors.Is(err, arigo.ErrOptionNotChangeable))
+
+	results, err := client.Batch().
+		ChangeGlobalOptions(arigo.GlobalOptions{RPCSecret: "secret"}).
+		GetGlobalOptions().
+		Do()
+	require.NoError(t, err)
+	assert.True(t, errors.Is(results[0].Err, arigo.ErrOptionNotChangeable))
+	assert.NoError(t, results[1].Err)
+	assert.IsType(t, arigo.GlobalOptions{}, results[1].Value)
+}
@@ -0,0 +1,51 @@
+package arigo
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobalOptionsFormat(t *testing.T) {
+	data := []byte(`{
+		"dir": "/downloads",
+		"max-concurrent-downloads": "5",
+		"max-overall-download-limit": "0",
+		"rpc-listen-port": "6800",
+		"save-session": "/etc/aria2/session"
+	}`)
+
+	var options GlobalOptions
+	require.NoError(t, json.Unmarshal(data, &options))
+
+	assert.Equal(t, GlobalOptions{
+		Options:                Options{Dir: "/downloads"},
+		MaxConcurrentDownloads: 5,
+		RPCListenPort:          6800,
+		SaveSession:            "/etc/aria2/session",
+	}, options)
+
+	data, err := json.Marshal(GlobalOptions{Options: Options{Split: 4}, MaxOverallDownloadLimit: 1024})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"split": "4", "max-overall-download-limit": "1024"}`, string(data))
+}
+
+func TestGlobalOptionsValidateRuntime(t *testing.T) {
+	options := GlobalOptions{
+		Options:                 Options{Split: 4, MaxDownloadLimit: 1024},
+		MaxConcurrentDownloads:  3,
+		MaxOverallDownloadLimit: 1024,
+		LogLevel:                "info",
+	}
+	assert.NoError(t, validateRuntime(options.Values()))
+
+	options.RPCListenPort = 6801
+	options.Out = "file"
+	err := validateRuntime(options.Values())
+	assert.Equal(t, &OptionError{Options: []string{"out", "rpc-listen-port"}}, err)
+	assert.True(t, errors.Is(err, ErrOptionNotChangeable))
+	assert.EqualError(t, err, "options can't be changed at runtime: out, rpc-listen-port")
+}
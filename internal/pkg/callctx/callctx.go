@@ -0,0 +1,40 @@
+// Package callctx passes the context of a call on to the rpc2.Codec which sends it.
+//
+// rpc2 doesn't know about contexts, so a call whose context is done is
+// abandoned but stays pending until its response arrives.
+// Codecs which understand Params use the context to cancel the request
+// and to deliver an error response for the call in its place.
+package callctx
+
+import "context"
+
+// Params are the parameters of a call together with its context.
+// They must only be passed to codecs which understand them.
+type Params struct {
+	Ctx    context.Context
+	Params interface{}
+}
+
+// Split returns the context and the parameters of param.
+// If param isn't *Params, the context is context.Background().
+func Split(param interface{}) (context.Context, interface{}) {
+	if p, ok := param.(*Params); ok {
+		return p.Ctx, p.Params
+	}
+	return context.Background(), param
+}
+
+// WithDone returns a copy of ctx which is also canceled once done is closed.
+// Codecs use it to cancel their pending requests when they're closed.
+// cancel has to be called once the request has finished.
+func WithDone(ctx context.Context, done <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
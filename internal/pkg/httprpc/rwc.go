@@ -0,0 +1,150 @@
+// Package httprpc provides a ReadWriteCloser which sends every message
+// as a separate HTTP POST request.
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/siku2/arigo/internal/pkg/callctx"
+)
+
+// ReadWriteCloser is a rwc based on HTTP requests.
+// Every call to Write is sent as the body of a POST request and
+// the response bodies can be read in the order they arrive.
+type ReadWriteCloser struct {
+	url    string
+	client *http.Client
+	header http.Header
+
+	pr  *io.PipeReader
+	pw  *io.PipeWriter
+	mut sync.Mutex // makes sure responses aren't interleaved
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReadWriteCloser creates a new rwc which posts to the given url.
+// The given header is added to every request.
+// If client is nil, http.DefaultClient is used.
+func NewReadWriteCloser(url string, client *http.Client, header http.Header) *ReadWriteCloser {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	pr, pw := io.Pipe()
+	return &ReadWriteCloser{
+		url:    url,
+		client: client,
+		header: header,
+		pr:     pr,
+		pw:     pw,
+		closed: make(chan struct{}),
+	}
+}
+
+// Read reads the received responses into p
+func (rwc *ReadWriteCloser) Read(p []byte) (n int, err error) {
+	return rwc.pr.Read(p)
+}
+
+// Write sends p as a new request.
+// p must contain a complete JSON-RPC message.
+// The request is performed in the background, its response
+// becomes available to Read once it has been received.
+func (rwc *ReadWriteCloser) Write(p []byte) (n int, err error) {
+	return rwc.WriteContext(context.Background(), p)
+}
+
+// WriteContext is like Write but the request is canceled when ctx is done.
+// The response of a canceled request is an error response.
+func (rwc *ReadWriteCloser) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	body := make([]byte, len(p))
+	copy(body, p)
+
+	go rwc.post(ctx, body)
+
+	return len(p), nil
+}
+
+func (rwc *ReadWriteCloser) post(ctx context.Context, body []byte) {
+	ctx, cancel := callctx.WithDone(ctx, rwc.closed)
+	defer cancel()
+
+	reply, err := rwc.do(ctx, body)
+	if err != nil {
+		reply = errorReply(body, err)
+		if reply == nil {
+			return
+		}
+	}
+
+	rwc.mut.Lock()
+	defer rwc.mut.Unlock()
+	_, _ = rwc.pw.Write(reply)
+}
+
+func (rwc *ReadWriteCloser) do(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, rwc.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range rwc.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := rwc.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reply, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// aria2 answers rejected calls with an error status but still sends
+	// a valid JSON-RPC response, so only the body decides.
+	if !json.Valid(reply) {
+		return nil, fmt.Errorf("httprpc: unexpected response: %s", resp.Status)
+	}
+
+	return reply, nil
+}
+
+// errorReply creates a JSON-RPC response carrying err for the
+// request in body. It returns nil if the request doesn't expect a response.
+func errorReply(body []byte, err error) []byte {
+	var req struct {
+		ID *json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.ID == nil {
+		return nil
+	}
+
+	reply, _ := json.Marshal(struct {
+		ID     *json.RawMessage `json:"id"`
+		Result interface{}      `json:"result"`
+		Error  string           `json:"error"`
+	}{req.ID, nil, err.Error()})
+
+	return reply
+}
+
+// Close closes the rwc and cancels the pending requests.
+// Pending and future reads return io.EOF.
+func (rwc *ReadWriteCloser) Close() error {
+	rwc.closeOnce.Do(func() {
+		close(rwc.closed)
+	})
+	return rwc.pw.Close()
+}
@@ -0,0 +1,325 @@
+// Package jsonrpc provides a JSON-RPC rpc2.Codec which understands the
+// error objects sent by aria2.
+//
+// The codec of the rpc2 package only accepts errors which are strings and
+// stops reading as soon as it encounters an error object.
+// This codec passes the raw JSON of the error on to the rpc2 client instead,
+// so the error of a call is a rpc2.ServerError containing either
+// a JSON object like {"code":1,"message":"Unauthorized"} or a JSON string.
+// Use DecodeError to decode it.
+//
+// Calls whose parameters are *callctx.Params are abandoned once their context
+// is done: the codec reports an error response for them so the rpc2 client
+// doesn't keep them pending, and the response aria2 sends later is ignored.
+// If conn implements ContextWriter, the context is passed on to it as well.
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/cenkalti/rpc2"
+	"github.com/siku2/arigo/internal/pkg/callctx"
+)
+
+// ContextWriter is implemented by connections which can abandon
+// writing a message once ctx is done, like the HTTP transport.
+type ContextWriter interface {
+	WriteContext(ctx context.Context, p []byte) (n int, err error)
+}
+
+var (
+	errMissingParams = errors.New("jsonrpc: request body missing params")
+	errInvalidSeq    = errors.New("jsonrpc: invalid sequence number in response")
+)
+
+var null = json.RawMessage("null")
+
+// message is a request or a response
+type message struct {
+	Method string           `json:"method"`
+	Params *json.RawMessage `json:"params"`
+	ID     *json.RawMessage `json:"id"`
+	Result *json.RawMessage `json:"result"`
+	Error  *json.RawMessage `json:"error"`
+}
+
+type clientRequest struct {
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+	ID     *uint64     `json:"id,omitempty"`
+}
+
+type serverResponse struct {
+	ID     *json.RawMessage `json:"id"`
+	Result interface{}      `json:"result"`
+	Error  interface{}      `json:"error"`
+}
+
+// incoming is a message read from the connection.
+type incoming struct {
+	msg message
+	err error
+}
+
+type codec struct {
+	dec  *json.Decoder
+	enc  *json.Encoder
+	conn io.ReadWriteCloser
+
+	readOnce  sync.Once
+	incoming  chan incoming // messages read from conn and responses of abandoned calls
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	msg message // message which is currently read
+
+	// calls maps the seq of calls which can be abandoned to a channel
+	// which is closed when their response arrives.
+	callsMut sync.Mutex
+	calls    map[uint64]chan struct{}
+
+	// Requests can use arbitrary JSON values as their id but rpc2 expects
+	// sequence numbers, the original ids are kept in pending.
+	mut     sync.Mutex
+	pending map[uint64]*json.RawMessage
+	seq     uint64
+}
+
+// NewCodec returns a new rpc2.Codec using JSON-RPC on conn.
+func NewCodec(conn io.ReadWriteCloser) rpc2.Codec {
+	return &codec{
+		dec:      json.NewDecoder(conn),
+		enc:      json.NewEncoder(conn),
+		conn:     conn,
+		incoming: make(chan incoming),
+		closed:   make(chan struct{}),
+		calls:    make(map[uint64]chan struct{}),
+		pending:  make(map[uint64]*json.RawMessage),
+	}
+}
+
+// read reads the messages from the connection until it fails.
+func (c *codec) read() {
+	for {
+		var in incoming
+		in.err = c.dec.Decode(&in.msg)
+
+		select {
+		case c.incoming <- in:
+		case <-c.closed:
+			return
+		}
+
+		if in.err != nil {
+			return
+		}
+	}
+}
+
+func (c *codec) ReadHeader(req *rpc2.Request, resp *rpc2.Response) error {
+	// reading starts lazily so that a codec which is only used
+	// for writing doesn't consume its own output
+	c.readOnce.Do(func() {
+		go c.read()
+	})
+
+	var in incoming
+	select {
+	case in = <-c.incoming:
+	case <-c.closed:
+		return io.EOF
+	}
+	if in.err != nil {
+		return in.err
+	}
+	c.msg = in.msg
+
+	if c.msg.Method != "" {
+		req.Method = c.msg.Method
+		req.Seq = 0
+
+		// requests without an id are notifications
+		if c.msg.ID != nil {
+			c.mut.Lock()
+			c.seq++
+			c.pending[c.seq] = c.msg.ID
+			req.Seq = c.seq
+			c.mut.Unlock()
+		}
+
+		return nil
+	}
+
+	if c.msg.ID == nil {
+		return errors.New("jsonrpc: response without id")
+	}
+	if err := json.Unmarshal(*c.msg.ID, &resp.Seq); err != nil {
+		return err
+	}
+	c.responded(resp.Seq)
+
+	resp.Error = ""
+	if c.msg.Error != nil && string(*c.msg.Error) != "null" {
+		resp.Error = string(*c.msg.Error)
+	} else if c.msg.Result == nil {
+		resp.Error = `"unspecified error"`
+	}
+
+	return nil
+}
+
+func (c *codec) ReadRequestBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+	if c.msg.Params == nil {
+		return errMissingParams
+	}
+
+	// params are positional, anything but a slice is the first parameter
+	if rt := reflect.TypeOf(x); rt.Kind() == reflect.Ptr && rt.Elem().Kind() == reflect.Slice {
+		return json.Unmarshal(*c.msg.Params, x)
+	}
+
+	return json.Unmarshal(*c.msg.Params, &[]interface{}{x})
+}
+
+func (c *codec) ReadResponseBody(x interface{}) error {
+	if x == nil || c.msg.Result == nil {
+		return nil
+	}
+	return json.Unmarshal(*c.msg.Result, x)
+}
+
+func (c *codec) WriteRequest(r *rpc2.Request, param interface{}) error {
+	ctx, param := callctx.Split(param)
+	req := clientRequest{Method: r.Method, Params: param}
+
+	if param == nil || reflect.TypeOf(param).Kind() != reflect.Slice {
+		req.Params = []interface{}{param}
+	}
+
+	// requests with seq 0 are notifications
+	if r.Seq != 0 {
+		seq := r.Seq
+		req.ID = &seq
+
+		if ctx.Done() != nil {
+			c.watch(ctx, seq)
+		}
+	}
+
+	if w, ok := c.conn.(ContextWriter); ok {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		_, err = w.WriteContext(ctx, append(data, '\n'))
+		return err
+	}
+
+	return c.enc.Encode(req)
+}
+
+// watch abandons the call once ctx is done, unless its response arrived before.
+func (c *codec) watch(ctx context.Context, seq uint64) {
+	done := make(chan struct{})
+	c.callsMut.Lock()
+	c.calls[seq] = done
+	c.callsMut.Unlock()
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		c.callsMut.Lock()
+		_, pending := c.calls[seq]
+		delete(c.calls, seq)
+		c.callsMut.Unlock()
+		if !pending {
+			return
+		}
+
+		id := json.RawMessage(strconv.FormatUint(seq, 10))
+		errMsg, _ := json.Marshal(ctx.Err().Error())
+		errRaw := json.RawMessage(errMsg)
+
+		select {
+		case c.incoming <- incoming{msg: message{ID: &id, Error: &errRaw}}:
+		case <-c.closed:
+		}
+	}()
+}
+
+// responded marks the call as answered so it isn't abandoned anymore.
+func (c *codec) responded(seq uint64) {
+	c.callsMut.Lock()
+	defer c.callsMut.Unlock()
+
+	if done, ok := c.calls[seq]; ok {
+		close(done)
+		delete(c.calls, seq)
+	}
+}
+
+func (c *codec) WriteResponse(r *rpc2.Response, x interface{}) error {
+	c.mut.Lock()
+	id, ok := c.pending[r.Seq]
+	delete(c.pending, r.Seq)
+	c.mut.Unlock()
+
+	if !ok {
+		return errInvalidSeq
+	}
+	if id == nil {
+		id = &null
+	}
+
+	resp := serverResponse{ID: id}
+	if r.Error == "" {
+		resp.Result = x
+	} else {
+		resp.Error = r.Error
+	}
+
+	return c.enc.Encode(resp)
+}
+
+func (c *codec) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.conn.Close()
+}
+
+// Error is an error object of a JSON-RPC response.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// DecodeError decodes the error of a response read by the codec.
+// The error is nil if s doesn't contain an error object.
+// Errors which are plain strings are returned as the message.
+func DecodeError(s string) (message string, err *Error) {
+	var e Error
+	if json.Unmarshal([]byte(s), &e) == nil && e.Message != "" {
+		return e.Message, &e
+	}
+
+	if json.Unmarshal([]byte(s), &message) == nil {
+		return message, nil
+	}
+
+	// not encoded by this codec
+	return s, nil
+}
@@ -0,0 +1,120 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/rpc2"
+	"github.com/siku2/arigo/internal/pkg/callctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type buffer struct {
+	*bytes.Buffer
+}
+
+func (buffer) Close() error { return nil }
+
+func TestReadResponse(t *testing.T) {
+	conn := buffer{bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"result":"OK"}` +
+		`{"jsonrpc":"2.0","id":2,"error":{"code":1,"message":"Unauthorized"}}` +
+		`{"jsonrpc":"2.0","id":3,"result":null,"error":"connection refused"}`)}
+	c := NewCodec(conn)
+
+	var req rpc2.Request
+	var resp rpc2.Response
+	var result string
+
+	require.NoError(t, c.ReadHeader(&req, &resp))
+	assert.Equal(t, uint64(1), resp.Seq)
+	assert.Empty(t, resp.Error)
+	require.NoError(t, c.ReadResponseBody(&result))
+	assert.Equal(t, "OK", result)
+
+	require.NoError(t, c.ReadHeader(&req, &resp))
+	assert.Equal(t, uint64(2), resp.Seq)
+	message, err := DecodeError(resp.Error)
+	assert.Equal(t, "Unauthorized", message)
+	assert.Equal(t, &Error{Code: 1, Message: "Unauthorized"}, err)
+
+	require.NoError(t, c.ReadHeader(&req, &resp))
+	assert.Equal(t, uint64(3), resp.Seq)
+	message, err = DecodeError(resp.Error)
+	assert.Equal(t, "connection refused", message)
+	assert.Nil(t, err)
+}
+
+func TestReadNotification(t *testing.T) {
+	conn := buffer{bytes.NewBufferString(`{"jsonrpc":"2.0","method":"aria2.onDownloadStart","params":[{"gid":"2089b05ecca3d829"}]}`)}
+	c := NewCodec(conn)
+
+	var req rpc2.Request
+	var resp rpc2.Response
+	require.NoError(t, c.ReadHeader(&req, &resp))
+	assert.Equal(t, "aria2.onDownloadStart", req.Method)
+	assert.Equal(t, uint64(0), req.Seq)
+
+	var event struct {
+		GID string `json:"gid"`
+	}
+	require.NoError(t, c.ReadRequestBody(&event))
+	assert.Equal(t, "2089b05ecca3d829", event.GID)
+}
+
+func TestWriteRequest(t *testing.T) {
+	conn := buffer{&bytes.Buffer{}}
+	c := NewCodec(conn)
+
+	require.NoError(t, c.WriteRequest(&rpc2.Request{Seq: 1, Method: "aria2.tellStatus"}, []string{"token:secret", "2089b05ecca3d829"}))
+	require.NoError(t, c.WriteRequest(&rpc2.Request{Seq: 2, Method: "aria2.getVersion"}, "token:secret"))
+
+	data, err := ioutil.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, `{"method":"aria2.tellStatus","params":["token:secret","2089b05ecca3d829"],"id":1}`+"\n"+
+		`{"method":"aria2.getVersion","params":["token:secret"],"id":2}`+"\n", string(data))
+}
+
+type pipeConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (pipeConn) Close() error { return nil }
+
+func TestAbandonCall(t *testing.T) {
+	// aria2 reads the requests but never answers
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	c := NewCodec(pipeConn{pr, ioutil.Discard})
+	client := rpc2.NewClientWithCodec(c)
+	go client.Run()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var reply string
+	call := client.Go("aria2.getVersion", &callctx.Params{Ctx: ctx, Params: []interface{}{}}, &reply, make(chan *rpc2.Call, 1))
+	cancel()
+
+	select {
+	case <-call.Done:
+		message, _ := DecodeError(call.Error.Error())
+		assert.Equal(t, context.Canceled.Error(), message)
+	case <-time.After(time.Second):
+		t.Fatal("abandoned call is still pending")
+	}
+
+	// the late response is ignored
+	_, err := pw.Write([]byte(`{"id":1,"result":"OK"}`))
+	require.NoError(t, err)
+	call = client.Go("aria2.getVersion", []interface{}{}, &reply, make(chan *rpc2.Call, 1))
+	_, err = pw.Write([]byte(`{"id":2,"result":"1.36.0"}`))
+	require.NoError(t, err)
+	<-call.Done
+	require.NoError(t, call.Error)
+	assert.Equal(t, "1.36.0", reply)
+}
@@ -3,20 +3,102 @@
 package wsrpc
 
 import (
-	"github.com/gorilla/websocket"
+	"errors"
 	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
+// ErrKeepAliveTimeout is returned by Read when the peer didn't answer
+// the keepalive pings in time.
+var ErrKeepAliveTimeout = errors.New("wsrpc: keepalive timeout")
+
 // ReadWriteCloser is a rwc based on WebSockets
 type ReadWriteCloser struct {
 	ws *websocket.Conn
 	r  io.Reader
 	w  io.WriteCloser
+
+	timeout  time.Duration // keepalive timeout, 0 if keepalive is disabled
+	stop     chan struct{}
+	stopOnce *sync.Once
+
+	err    error // first error encountered while reading
+	errMut *sync.Mutex
 }
 
 // NewReadWriteCloser creates a new rwc from a WebSocket connection
 func NewReadWriteCloser(ws *websocket.Conn) ReadWriteCloser {
-	return ReadWriteCloser{ws: ws}
+	return ReadWriteCloser{
+		ws:       ws,
+		stop:     make(chan struct{}),
+		stopOnce: &sync.Once{},
+		errMut:   &sync.Mutex{},
+	}
+}
+
+// KeepAlive starts sending a ping every interval.
+// If no message is received from the peer for longer than timeout,
+// the connection is considered dead and Read fails with ErrKeepAliveTimeout.
+// Pongs count as messages, so the timeout has to be larger than interval.
+// If it isn't, three times the interval is used instead.
+//
+// KeepAlive must be called before the rwc is used.
+func (rwc *ReadWriteCloser) KeepAlive(interval, timeout time.Duration) {
+	if timeout <= interval {
+		timeout = 3 * interval
+	}
+
+	rwc.timeout = timeout
+	_ = rwc.ws.SetReadDeadline(time.Now().Add(timeout))
+	rwc.ws.SetPongHandler(func(string) error {
+		return rwc.ws.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	go rwc.ping(interval)
+}
+
+func (rwc *ReadWriteCloser) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := rwc.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval))
+			if err != nil {
+				return
+			}
+		case <-rwc.stop:
+			return
+		}
+	}
+}
+
+// Err returns the error which caused reading to fail.
+// It returns nil as long as reading works.
+func (rwc *ReadWriteCloser) Err() error {
+	rwc.errMut.Lock()
+	defer rwc.errMut.Unlock()
+
+	return rwc.err
+}
+
+func (rwc *ReadWriteCloser) setErr(err error) error {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() && rwc.timeout > 0 {
+		err = ErrKeepAliveTimeout
+	}
+
+	rwc.errMut.Lock()
+	defer rwc.errMut.Unlock()
+
+	if rwc.err == nil {
+		rwc.err = err
+	}
+	return err
 }
 
 // Read reads from the WebSocket into p
@@ -24,7 +106,11 @@ func (rwc *ReadWriteCloser) Read(p []byte) (n int, err error) {
 	if rwc.r == nil {
 		_, rwc.r, err = rwc.ws.NextReader()
 		if err != nil {
-			return 0, err
+			return 0, rwc.setErr(err)
+		}
+
+		if rwc.timeout > 0 {
+			_ = rwc.ws.SetReadDeadline(time.Now().Add(rwc.timeout))
 		}
 	}
 
@@ -38,6 +124,7 @@ func (rwc *ReadWriteCloser) Read(p []byte) (n int, err error) {
 			break
 		}
 		if err != nil {
+			err = rwc.setErr(err)
 			break
 		}
 	}
@@ -71,6 +158,10 @@ func (rwc *ReadWriteCloser) Write(p []byte) (n int, err error) {
 
 // Close the rwc and the underlying WebSocket connection
 func (rwc *ReadWriteCloser) Close() error {
+	rwc.stopOnce.Do(func() {
+		close(rwc.stop)
+	})
+
 	var err error
 	if rwc.w != nil {
 		if err = rwc.w.Close(); err != nil {
@@ -78,9 +169,10 @@ func (rwc *ReadWriteCloser) Close() error {
 		}
 		rwc.w = nil
 	}
+	// ws is kept so that concurrent reads fail with an error
+	// instead of dereferencing nil.
 	if rwc.ws != nil {
 		err = rwc.ws.Close()
-		rwc.ws = nil
 	}
 	return err
 }
@@ -0,0 +1,320 @@
+// Package xmlrpc provides a rpc2.Codec which speaks XML-RPC over HTTP.
+//
+// Values are converted to and from their JSON representation so the
+// JSON struct tags used for the JSON-RPC interface apply to XML-RPC as well.
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/cenkalti/rpc2"
+	"github.com/siku2/arigo/internal/pkg/callctx"
+	"github.com/siku2/arigo/internal/pkg/jsonrpc"
+	"github.com/siku2/arigo/pkg/aria2proto"
+)
+
+var (
+	errNotification = errors.New("xmlrpc: notifications are not supported")
+	errRequest      = errors.New("xmlrpc: can't handle requests")
+)
+
+type response struct {
+	seq    uint64
+	result []byte // JSON encoded result
+	err    error
+}
+
+type codec struct {
+	url    string
+	client *http.Client
+	header http.Header
+
+	responses chan response
+	current   response
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCodec creates a new codec which posts all requests to url.
+// The given header is added to every request.
+// If client is nil, http.DefaultClient is used.
+func NewCodec(url string, client *http.Client, header http.Header) rpc2.Codec {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &codec{
+		url:       url,
+		client:    client,
+		header:    header,
+		responses: make(chan response),
+		closed:    make(chan struct{}),
+	}
+}
+
+// ReadHeader waits for the next response.
+// Requests are never read because XML-RPC doesn't support them.
+func (c *codec) ReadHeader(_ *rpc2.Request, resp *rpc2.Response) error {
+	select {
+	case c.current = <-c.responses:
+	case <-c.closed:
+		return io.EOF
+	}
+
+	resp.Seq = c.current.seq
+	resp.Error = ""
+	if f, ok := c.current.err.(*fault); ok {
+		// report faults the same way as the JSON-RPC codec reports error objects
+		data, err := json.Marshal(jsonrpc.Error{Code: f.Code, Message: f.String})
+		if err != nil {
+			return err
+		}
+		resp.Error = string(data)
+	} else if c.current.err != nil {
+		resp.Error = c.current.err.Error()
+	}
+
+	return nil
+}
+
+func (c *codec) ReadRequestBody(interface{}) error {
+	return errRequest
+}
+
+func (c *codec) ReadResponseBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+	return json.Unmarshal(c.current.result, x)
+}
+
+func (c *codec) WriteRequest(r *rpc2.Request, param interface{}) error {
+	if r.Seq == 0 {
+		return errNotification
+	}
+
+	// the request of an abandoned call is canceled,
+	// its response is the error of the request
+	ctx, param := callctx.Split(param)
+	body, err := encodeRequest(r.Method, param)
+	if err != nil {
+		return err
+	}
+
+	go c.post(ctx, r.Seq, r.Method, body)
+	return nil
+}
+
+func (c *codec) WriteResponse(*rpc2.Response, interface{}) error {
+	return errRequest
+}
+
+func (c *codec) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *codec) post(ctx context.Context, seq uint64, method string, body []byte) {
+	ctx, cancel := callctx.WithDone(ctx, c.closed)
+	defer cancel()
+
+	resp := response{seq: seq}
+	resp.result, resp.err = c.do(ctx, method, body)
+
+	select {
+	case c.responses <- resp:
+	case <-c.closed:
+	}
+}
+
+func (c *codec) do(ctx context.Context, method string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeResponse(method, data)
+}
+
+// encodeRequest encodes a methodCall.
+// param is either a slice of parameters or a single parameter.
+func encodeRequest(method string, param interface{}) ([]byte, error) {
+	params, err := toJSONValue(param)
+	if err != nil {
+		return nil, err
+	}
+
+	paramList, ok := params.([]interface{})
+	if !ok {
+		paramList = []interface{}{params}
+	}
+	markBinary(method, paramList)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<methodCall><methodName>")
+	if err := xml.EscapeText(&buf, []byte(method)); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</methodName><params>")
+	for _, p := range paramList {
+		buf.WriteString("<param>")
+		if err := writeValue(&buf, p); err != nil {
+			return nil, err
+		}
+		buf.WriteString("</param>")
+	}
+	buf.WriteString("</params></methodCall>")
+
+	return buf.Bytes(), nil
+}
+
+// toJSONValue converts v to the generic value obtained by
+// marshalling it to JSON and decoding it again.
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value interface{}
+	err = dec.Decode(&value)
+	return value, err
+}
+
+// markBinary marks the uploaded file of the methods which expect one
+// so that it's sent using the base64 type.
+func markBinary(method string, params []interface{}) {
+	switch method {
+	case aria2proto.AddTorrent, aria2proto.AddMetalink:
+		for i, p := range params {
+			s, ok := p.(string)
+			if ok && !strings.HasPrefix(s, "token:") {
+				params[i] = base64Value(s)
+				return
+			}
+		}
+	case aria2proto.Multicall:
+		for _, p := range params {
+			calls, _ := p.([]interface{})
+			for _, call := range calls {
+				m, _ := call.(map[string]interface{})
+				name, _ := m["methodName"].(string)
+				callParams, _ := m["params"].([]interface{})
+				markBinary(name, callParams)
+			}
+		}
+	}
+}
+
+type methodResponse struct {
+	Params []value `xml:"params>param>value"`
+	Fault  *value  `xml:"fault>value"`
+}
+
+// fault is the error returned by an XML-RPC server
+type fault struct {
+	Code   int    `json:"faultCode"`
+	String string `json:"faultString"`
+}
+
+func (f *fault) Error() string {
+	return f.String
+}
+
+// decodeResponse decodes a methodResponse and returns its result encoded as JSON.
+// Faults are returned as errors.
+func decodeResponse(method string, data []byte) ([]byte, error) {
+	var resp methodResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Fault != nil {
+		return nil, decodeFault(resp.Fault)
+	}
+
+	if len(resp.Params) != 1 {
+		return nil, fmt.Errorf("xmlrpc: expected 1 return value, got %d", len(resp.Params))
+	}
+
+	result, err := resp.Params[0].toJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	if method == aria2proto.Multicall {
+		convertMulticallFaults(result)
+	}
+
+	return json.Marshal(result)
+}
+
+func decodeFault(v *value) error {
+	raw, err := v.toJSON()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	var f fault
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	return &f
+}
+
+// convertMulticallFaults converts the faults in the result of a multicall
+// to the error objects used by the JSON-RPC interface.
+func convertMulticallFaults(result interface{}) {
+	results, _ := result.([]interface{})
+	for i, r := range results {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if _, isFault := m["faultCode"]; isFault {
+			results[i] = map[string]interface{}{
+				"code":    m["faultCode"],
+				"message": m["faultString"],
+			}
+		}
+	}
+}
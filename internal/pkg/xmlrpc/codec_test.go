@@ -0,0 +1,79 @@
+package xmlrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeRequest(t *testing.T) {
+	params := []interface{}{
+		"token:secret",
+		"dG9ycmVudA==",
+		[]string{"http://example.org/a&b"},
+		map[string]string{"split": "4"},
+		3,
+	}
+
+	body, err := encodeRequest("aria2.addTorrent", params)
+	require.NoError(t, err)
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<methodCall><methodName>aria2.addTorrent</methodName><params>`+
+		`<param><value><string>token:secret</string></value></param>`+
+		`<param><value><base64>dG9ycmVudA==</base64></value></param>`+
+		`<param><value><array><data><value><string>http://example.org/a&amp;b</string></value></data></array></value></param>`+
+		`<param><value><struct><member><name>split</name><value><string>4</string></value></member></struct></value></param>`+
+		`<param><value><int>3</int></value></param>`+
+		`</params></methodCall>`, string(body))
+}
+
+func TestDecodeResponse(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+	<methodResponse><params><param><value><struct>
+		<member><name>gid</name><value><string>2089b05ecca3d829</string></value></member>
+		<member><name>numPieces</name><value>34</value></member>
+		<member><name>position</name><value><int>2</int></value></member>
+		<member><name>seeder</name><value><boolean>1</boolean></value></member>
+		<member><name>files</name><value><array><data>
+			<value><struct><member><name>index</name><value><string>1</string></value></member></struct></value>
+		</data></array></value></member>
+	</struct></value></param></params></methodResponse>`)
+
+	result, err := decodeResponse("aria2.tellStatus", data)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"gid": "2089b05ecca3d829",
+		"numPieces": "34",
+		"position": 2,
+		"seeder": true,
+		"files": [{"index": "1"}]
+	}`, string(result))
+}
+
+func TestDecodeFault(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+	<methodResponse><fault><value><struct>
+		<member><name>faultCode</name><value><int>1</int></value></member>
+		<member><name>faultString</name><value><string>GID 2089b05ecca3d829 is not found</string></value></member>
+	</struct></value></fault></methodResponse>`)
+
+	_, err := decodeResponse("aria2.tellStatus", data)
+	assert.EqualError(t, err, "GID 2089b05ecca3d829 is not found")
+}
+
+func TestDecodeMulticallFault(t *testing.T) {
+	data := []byte(`<methodResponse><params><param><value><array><data>
+		<value><array><data><value><string>OK</string></value></data></array></value>
+		<value><struct>
+			<member><name>faultCode</name><value><int>1</int></value></member>
+			<member><name>faultString</name><value><string>Unauthorized</string></value></member>
+		</struct></value>
+	</data></array></value></param></params></methodResponse>`)
+
+	result, err := decodeResponse("system.multicall", data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[["OK"], {"code": 1, "message": "Unauthorized"}]`, string(result))
+}
@@ -0,0 +1,164 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// base64Value is a string which is already base64 encoded and
+// must be sent using the base64 type.
+type base64Value string
+
+// writeValue writes v as an XML-RPC value.
+// v must be one of the types produced by decoding JSON with
+// json.Decoder.UseNumber or a base64Value.
+// nil is written as an empty array because aria2 doesn't support nil
+// values and the client only produces them for empty slices.
+func writeValue(buf *bytes.Buffer, v interface{}) error {
+	buf.WriteString("<value>")
+
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("<array><data></data></array>")
+	case string:
+		buf.WriteString("<string>")
+		if err := xml.EscapeText(buf, []byte(v)); err != nil {
+			return err
+		}
+		buf.WriteString("</string>")
+	case base64Value:
+		buf.WriteString("<base64>")
+		buf.WriteString(string(v))
+		buf.WriteString("</base64>")
+	case bool:
+		if v {
+			buf.WriteString("<boolean>1</boolean>")
+		} else {
+			buf.WriteString("<boolean>0</boolean>")
+		}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			buf.WriteString("<int>" + v.String() + "</int>")
+		} else {
+			buf.WriteString("<double>" + v.String() + "</double>")
+		}
+	case []interface{}:
+		buf.WriteString("<array><data>")
+		for _, item := range v {
+			if err := writeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</data></array>")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("<struct>")
+		for _, key := range keys {
+			buf.WriteString("<member><name>")
+			if err := xml.EscapeText(buf, []byte(key)); err != nil {
+				return err
+			}
+			buf.WriteString("</name>")
+			if err := writeValue(buf, v[key]); err != nil {
+				return err
+			}
+			buf.WriteString("</member>")
+		}
+		buf.WriteString("</struct>")
+	default:
+		return fmt.Errorf("xmlrpc: unsupported type %T", v)
+	}
+
+	buf.WriteString("</value>")
+	return nil
+}
+
+type member struct {
+	Name  string `xml:"name"`
+	Value value  `xml:"value"`
+}
+
+type structValue struct {
+	Members []member `xml:"member"`
+}
+
+type arrayValue struct {
+	Values []value `xml:"data>value"`
+}
+
+// value is an XML-RPC value as it appears in a response.
+type value struct {
+	String   *string      `xml:"string"`
+	Int      *string      `xml:"int"`
+	I4       *string      `xml:"i4"`
+	Boolean  *string      `xml:"boolean"`
+	Double   *string      `xml:"double"`
+	Base64   *string      `xml:"base64"`
+	DateTime *string      `xml:"dateTime.iso8601"`
+	Nil      *struct{}    `xml:"nil"`
+	Struct   *structValue `xml:"struct"`
+	Array    *arrayValue  `xml:"array"`
+	Text     string       `xml:",chardata"` // values without a type are strings
+}
+
+// toJSON converts the value to the equivalent of a decoded JSON value.
+func (v *value) toJSON() (interface{}, error) {
+	switch {
+	case v.String != nil:
+		return *v.String, nil
+	case v.Int != nil:
+		return parseNumber(*v.Int)
+	case v.I4 != nil:
+		return parseNumber(*v.I4)
+	case v.Double != nil:
+		return parseNumber(*v.Double)
+	case v.Boolean != nil:
+		return strconv.ParseBool(strings.TrimSpace(*v.Boolean))
+	case v.Base64 != nil:
+		return strings.TrimSpace(*v.Base64), nil
+	case v.DateTime != nil:
+		return strings.TrimSpace(*v.DateTime), nil
+	case v.Nil != nil:
+		return nil, nil
+	case v.Struct != nil:
+		m := make(map[string]interface{}, len(v.Struct.Members))
+		for _, mem := range v.Struct.Members {
+			item, err := mem.Value.toJSON()
+			if err != nil {
+				return nil, err
+			}
+			m[mem.Name] = item
+		}
+		return m, nil
+	case v.Array != nil:
+		s := make([]interface{}, len(v.Array.Values))
+		for i := range v.Array.Values {
+			item, err := v.Array.Values[i].toJSON()
+			if err != nil {
+				return nil, err
+			}
+			s[i] = item
+		}
+		return s, nil
+	default:
+		return v.Text, nil
+	}
+}
+
+func parseNumber(s string) (json.Number, error) {
+	s = strings.TrimSpace(s)
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return "", err
+	}
+	return json.Number(s), nil
+}
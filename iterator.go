@@ -0,0 +1,183 @@
+package arigo
+
+import "context"
+
+// DefaultPageSize is the number of downloads requested per page
+// if PageOptions.PageSize is not set.
+const DefaultPageSize = 100
+
+// PageOptions configures how a StatusIterator walks a queue.
+type PageOptions struct {
+	// PageSize is the number of downloads requested per call.
+	// If zero, DefaultPageSize is used.
+	PageSize uint
+
+	// Keys of the status to request, like in TellStatus.
+	// The gid is always requested because it's used to detect queue changes.
+	// If empty, the whole status is requested.
+	Keys []string
+
+	// Reverse walks the queue from the back to the front.
+	Reverse bool
+}
+
+// tellFunc is TellWaitingContext or TellStoppedContext.
+type tellFunc func(ctx context.Context, offset int, num uint, keys ...string) ([]Status, error)
+
+// StatusIterator walks the waiting or stopped queue page by page.
+// The pages are only requested when they're needed.
+//
+// The queues can change between two pages, for example because a download
+// was started or removed. The iterator detects this using the GIDs of the
+// downloads it has already seen, so no download is returned twice and
+// none of the downloads which were in the queue the whole time is skipped.
+// Downloads which are added while iterating may or may not be returned.
+//
+// Use it like this:
+//
+//	it := client.IterWaiting(ctx, arigo.PageOptions{Keys: []string{"status"}})
+//	for it.Next() {
+//		status := it.Status()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle error
+//	}
+type StatusIterator struct {
+	ctx      context.Context
+	tell     tellFunc
+	pageSize uint
+	keys     []string
+	reverse  bool
+
+	pos  int // position of the next page counted from the start of the walk
+	seen map[string]bool
+	end  bool
+
+	buf     []Status
+	current Status
+	err     error
+}
+
+func newStatusIterator(ctx context.Context, tell tellFunc, opts PageOptions) *StatusIterator {
+	pageSize := opts.PageSize
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+
+	var keys []string
+	if len(opts.Keys) > 0 {
+		keys = append([]string{"gid"}, opts.Keys...)
+	}
+
+	return &StatusIterator{
+		ctx:      ctx,
+		tell:     tell,
+		pageSize: pageSize,
+		keys:     keys,
+		reverse:  opts.Reverse,
+		seen:     make(map[string]bool),
+	}
+}
+
+// IterWaiting returns an iterator over the waiting queue, including paused downloads.
+// The calls are canceled when ctx is done.
+func (c *Client) IterWaiting(ctx context.Context, opts PageOptions) *StatusIterator {
+	return newStatusIterator(ctx, c.TellWaitingContext, opts)
+}
+
+// IterStopped returns an iterator over the stopped downloads.
+// The calls are canceled when ctx is done.
+func (c *Client) IterStopped(ctx context.Context, opts PageOptions) *StatusIterator {
+	return newStatusIterator(ctx, c.TellStoppedContext, opts)
+}
+
+// Next advances to the next download, which is then available through Status.
+// It returns false when the end of the queue is reached or an error occurred.
+func (it *StatusIterator) Next() bool {
+	for len(it.buf) == 0 {
+		if it.end || it.err != nil {
+			return false
+		}
+
+		it.err = it.fetch()
+	}
+
+	it.current = it.buf[0]
+	it.buf = it.buf[1:]
+	return true
+}
+
+// Status returns the status of the current download.
+func (it *StatusIterator) Status() Status {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *StatusIterator) Err() error {
+	return it.err
+}
+
+// request requests num downloads starting at pos.
+// Negative offsets count from the back of the queue, in which case aria2
+// returns the downloads in reverse order.
+func (it *StatusIterator) request(pos int, num uint) ([]Status, error) {
+	offset := pos
+	if it.reverse {
+		offset = -pos - 1
+	}
+
+	return it.tell(it.ctx, offset, num, it.keys...)
+}
+
+// fetch requests the next page and buffers the downloads which weren't seen yet.
+func (it *StatusIterator) fetch() error {
+	start, num := it.pos, it.pageSize
+	overlap := start > 0
+	if overlap {
+		// request the download before the page as well to check whether the queue has shifted
+		start--
+		num++
+	}
+
+	page, err := it.request(start, num)
+	if err != nil {
+		return err
+	}
+	it.end = uint(len(page)) < num
+
+	if overlap && !it.seenAny(page) {
+		// So many downloads before the page were removed that none of the
+		// requested ones was seen yet, some may have moved before the page.
+		// Go back a page until one which was seen turns up.
+		it.pos -= int(it.pageSize)
+		if it.pos < 0 {
+			it.pos = 0
+		}
+		it.end = false
+		return nil
+	}
+
+	// All downloads which weren't seen yet are new, even the ones before
+	// a download which was seen, like the ones which took the place of
+	// a download which was moved to the back.
+	it.pos = start + len(page)
+
+	for _, status := range page {
+		if !it.seen[status.GID] {
+			it.seen[status.GID] = true
+			it.buf = append(it.buf, status)
+		}
+	}
+
+	return nil
+}
+
+// seenAny reports whether any download in page was seen already.
+func (it *StatusIterator) seenAny(page []Status) bool {
+	for _, status := range page {
+		if it.seen[status.GID] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package arigo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterWaiting(t *testing.T) {
+	server, client := newTestClient(t)
+
+	var gids []string
+	for i := 0; i < 5; i++ {
+		gid, err := client.AddURI([]string{"https://example.org/file"}, nil)
+		require.NoError(t, err)
+		gids = append(gids, gid.GID)
+	}
+
+	var walked []string
+	it := client.IterWaiting(context.Background(), arigo.PageOptions{PageSize: 2, Keys: []string{"status"}, Reverse: true})
+	for it.Next() {
+		status := it.Status()
+		assert.Equal(t, arigo.StatusWaiting, status.Status)
+		walked = append(walked, status.GID)
+
+		// starting the first download shifts the queue
+		if len(walked) == 3 {
+			require.NoError(t, server.StartDownload(gids[0]))
+		}
+	}
+	require.NoError(t, it.Err())
+
+	assert.Equal(t, []string{gids[4], gids[3], gids[2], gids[1]}, walked)
+}
@@ -0,0 +1,157 @@
+package arigo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeQueue implements the paging of aria2's tellWaiting and tellStopped.
+type fakeQueue struct {
+	gids  []string
+	keys  []string
+	calls int
+
+	// onCall is called before every call after the first one
+	onCall func(q *fakeQueue)
+}
+
+func (q *fakeQueue) tell(_ context.Context, offset int, num uint, keys ...string) ([]Status, error) {
+	q.calls++
+	if q.calls > 1 && q.onCall != nil {
+		q.onCall(q)
+	}
+	q.keys = keys
+
+	statuses := []Status{}
+	if offset >= 0 {
+		for i := offset; i < len(q.gids) && uint(len(statuses)) < num; i++ {
+			statuses = append(statuses, Status{GID: q.gids[i]})
+		}
+	} else {
+		for i := len(q.gids) + offset; i >= 0 && uint(len(statuses)) < num; i-- {
+			statuses = append(statuses, Status{GID: q.gids[i]})
+		}
+	}
+
+	return statuses, nil
+}
+
+func collectGIDs(t *testing.T, it *StatusIterator) []string {
+	gids := []string{}
+	for it.Next() {
+		gids = append(gids, it.Status().GID)
+	}
+	assert.NoError(t, it.Err())
+	return gids
+}
+
+func TestStatusIterator(t *testing.T) {
+	q := &fakeQueue{gids: []string{"a", "b", "c", "d", "e"}}
+
+	it := newStatusIterator(context.Background(), q.tell, PageOptions{PageSize: 2, Keys: []string{"status"}})
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, collectGIDs(t, it))
+	assert.Equal(t, []string{"gid", "status"}, q.keys)
+
+	it = newStatusIterator(context.Background(), q.tell, PageOptions{PageSize: 2, Reverse: true})
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, collectGIDs(t, it))
+	assert.Empty(t, q.keys)
+
+	q = &fakeQueue{gids: []string{}}
+	it = newStatusIterator(context.Background(), q.tell, PageOptions{})
+	assert.Empty(t, collectGIDs(t, it))
+}
+
+func TestStatusIteratorShift(t *testing.T) {
+	tests := []struct {
+		name     string
+		queue    []string // a to e if nil
+		reverse  bool
+		onCall   func(q *fakeQueue)
+		expected []string
+	}{
+		{
+			name:     "removed before page",
+			onCall:   func(q *fakeQueue) { q.gids = without(q.gids, "a") },
+			expected: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:     "last seen removed",
+			onCall:   func(q *fakeQueue) { q.gids = without(q.gids, "b") },
+			expected: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name: "inserted at front",
+			onCall: func(q *fakeQueue) {
+				if q.gids[0] != "x" {
+					q.gids = append([]string{"x"}, q.gids...)
+				}
+			},
+			expected: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:  "more than a page removed",
+			queue: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"},
+			onCall: func(q *fakeQueue) {
+				// a to j were seen
+				if q.calls == 6 {
+					q.gids = q.gids[5:]
+				}
+			},
+			expected: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"},
+		},
+		{
+			name:  "moved to the back",
+			queue: []string{"a", "b", "c", "d", "e", "f"},
+			onCall: func(q *fakeQueue) {
+				// a to d were seen
+				if q.calls == 3 {
+					q.gids = append(without(q.gids, "a"), "a")
+				}
+			},
+			expected: []string{"a", "b", "c", "d", "e", "f"},
+		},
+		{
+			name:     "reverse removed at back",
+			reverse:  true,
+			onCall:   func(q *fakeQueue) { q.gids = without(q.gids, "e") },
+			expected: []string{"e", "d", "c", "b", "a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue := test.queue
+			if queue == nil {
+				queue = []string{"a", "b", "c", "d", "e"}
+			}
+
+			q := &fakeQueue{gids: queue, onCall: test.onCall}
+			it := newStatusIterator(context.Background(), q.tell, PageOptions{PageSize: 2, Reverse: test.reverse})
+			assert.Equal(t, test.expected, collectGIDs(t, it))
+		})
+	}
+}
+
+func TestStatusIteratorError(t *testing.T) {
+	errFailed := errors.New("failed")
+	tell := func(context.Context, int, uint, ...string) ([]Status, error) {
+		return nil, errFailed
+	}
+
+	it := newStatusIterator(context.Background(), tell, PageOptions{})
+	assert.False(t, it.Next())
+	assert.Equal(t, errFailed, it.Err())
+}
+
+func without(gids []string, gid string) []string {
+	result := []string{}
+	for _, g := range gids {
+		if g != gid {
+			result = append(result, g)
+		}
+	}
+	return result
+}
@@ -2,11 +2,14 @@ package arigo
 
 import (
 	"encoding/json"
+	"strings"
 )
 
-// MethodCallError represents an error returned by aria2 for a MethodCall
+// MethodCallError represents an error returned by aria2 for a MethodCall.
+// Like RPCError, it matches ErrGIDNotFound, ErrUnauthorized,
+// ErrInvalidParams or ErrMethodNotFound using errors.Is.
 type MethodCallError struct {
-	Code    uint   `json:"code,string"`
+	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
 
@@ -14,13 +17,18 @@ func (e *MethodCallError) Error() string {
 	return e.Message
 }
 
+// Is reports whether target is the sentinel error matching the reason of e.
+func (e *MethodCallError) Is(target error) bool {
+	return target != nil && target == classifyError(e.Code, e.Message)
+}
+
 // MethodResult represents the result of a MethodCall
 // in a MultiCall operation.
 type MethodResult struct {
 	Result []byte // Raw JSON value returned
 
-	// Error encountered during the method call.
-	// This is likely to be a MethodCallError but it's
+	// Error encountered during the method call or nil if it succeeded.
+	// This is likely to be a *MethodCallError but it's
 	// not guaranteed.
 	Error error
 }
@@ -51,4 +59,56 @@ func NewMethodCall(methodName string, params ...interface{}) *MethodCall {
 	}
 }
 
-// TODO create constructors for all methods
+// multicallArgs returns the arguments of a system.multicall request for the methods.
+// system.multicall itself doesn't take the secret token, each call has to carry it instead.
+func (c *Client) multicallArgs(methods []*MethodCall) []interface{} {
+	calls := make([]MethodCall, len(methods))
+
+	for i, method := range methods {
+		params := method.Params
+		if !strings.HasPrefix(method.MethodName, "system.") && !hasToken(params) {
+			params = c.getArgs(params...)
+		} else if params == nil {
+			params = []interface{}{}
+		}
+
+		calls[i] = MethodCall{MethodName: method.MethodName, Params: params}
+	}
+
+	return []interface{}{calls}
+}
+
+// hasToken reports whether params start with a secret token.
+func hasToken(params []interface{}) bool {
+	if len(params) == 0 {
+		return false
+	}
+
+	token, ok := params[0].(string)
+	return ok && strings.HasPrefix(token, "token:")
+}
+
+// decodeMethodResults decodes the results of a system.multicall request.
+func decodeMethodResults(rawResults []json.RawMessage) []MethodResult {
+	results := make([]MethodResult, len(rawResults))
+
+	for i, rawResult := range rawResults {
+		// successful calls return an array containing the result,
+		// failed ones an error object
+		var resultArray []json.RawMessage
+		if json.Unmarshal(rawResult, &resultArray) == nil && len(resultArray) > 0 {
+			results[i] = MethodResult{Result: resultArray[0]}
+			continue
+		}
+
+		methodErr := &MethodCallError{}
+		if err := json.Unmarshal(rawResult, methodErr); err != nil {
+			results[i] = MethodResult{Error: err}
+			continue
+		}
+
+		results[i] = MethodResult{Error: methodErr}
+	}
+
+	return results
+}
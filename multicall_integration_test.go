@@ -0,0 +1,30 @@
+package arigo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiCallError(t *testing.T) {
+	_, client := newTestClient(t)
+
+	results, err := client.MultiCall(
+		arigo.NewMethodCall("aria2.getVersion"),
+		arigo.NewMethodCall("aria2.tellStatus", "0000000000000001"),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	var version arigo.VersionInfo
+	assert.NoError(t, results[0].Unmarshal(&version))
+	assert.NotEmpty(t, version.Version)
+
+	var methodErr *arigo.MethodCallError
+	require.True(t, errors.As(results[1].Error, &methodErr))
+	assert.Equal(t, 1, methodErr.Code)
+	assert.True(t, errors.Is(results[1].Error, arigo.ErrGIDNotFound))
+}
@@ -0,0 +1,29 @@
+package arigo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulticallArgs(t *testing.T) {
+	c := &Client{authToken: "secret"}
+
+	args := c.multicallArgs([]*MethodCall{
+		NewMethodCall("aria2.tellStatus", "2089b05ecca3d829"),
+		NewMethodCall("aria2.getVersion"),
+		NewMethodCall("aria2.getVersion", "token:other"),
+		NewMethodCall("system.listMethods"),
+	})
+
+	assert.Equal(t, []interface{}{[]MethodCall{
+		{MethodName: "aria2.tellStatus", Params: []interface{}{"token:secret", "2089b05ecca3d829"}},
+		{MethodName: "aria2.getVersion", Params: []interface{}{"token:secret"}},
+		{MethodName: "aria2.getVersion", Params: []interface{}{"token:other"}},
+		{MethodName: "system.listMethods", Params: []interface{}{}},
+	}}, args)
+
+	c.authToken = ""
+	args = c.multicallArgs([]*MethodCall{NewMethodCall("aria2.getVersion")})
+	assert.Equal(t, []interface{}{[]MethodCall{{MethodName: "aria2.getVersion", Params: []interface{}{}}}}, args)
+}
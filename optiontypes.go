@@ -0,0 +1,217 @@
+package arigo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Headers are custom HTTP headers like "X-Custom: value", one per element.
+// They're sent to aria2 as an array, which is how aria2 accepts multiple headers.
+// aria2 reports them as a single string with one header per line,
+// both forms are decoded.
+type Headers []string
+
+// HeadersFromHTTP converts h to Headers, sorted by name.
+func HeadersFromHTTP(h http.Header) Headers {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers Headers
+	for _, name := range names {
+		for _, value := range h[name] {
+			headers = append(headers, name+": "+value)
+		}
+	}
+	return headers
+}
+
+// HTTP converts the headers to an http.Header.
+// Headers without a colon are ignored.
+func (headers Headers) HTTP() http.Header {
+	h := http.Header{}
+	for _, header := range headers {
+		i := strings.IndexByte(header, ':')
+		if i < 0 {
+			continue
+		}
+		h.Add(strings.TrimSpace(header[:i]), strings.TrimSpace(header[i+1:]))
+	}
+	return h
+}
+
+// UnmarshalJSON decodes an array of headers or a string with one header per line.
+func (headers *Headers) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*headers = list
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	*headers = splitLines(s)
+	return nil
+}
+
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// TrackerList is a list of tracker announce URIs,
+// encoded as the comma separated list aria2 expects.
+type TrackerList []string
+
+// MarshalText joins the URIs with commas.
+func (list TrackerList) MarshalText() ([]byte, error) {
+	return []byte(strings.Join(list, ",")), nil
+}
+
+// UnmarshalText splits a comma separated list of URIs.
+func (list *TrackerList) UnmarshalText(text []byte) error {
+	*list = nil
+	for _, uri := range strings.Split(string(text), ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			*list = append(*list, uri)
+		}
+	}
+	return nil
+}
+
+// IndexRange is an inclusive range of file indexes.
+type IndexRange struct {
+	Start uint
+	End   uint
+}
+
+// IndexSet is a set of file indexes, like "1-5,8" for the SelectFile option.
+// The indexes start at 1, see File.Index.
+type IndexSet []IndexRange
+
+// NewIndexSet creates an IndexSet containing the indexes,
+// merging consecutive ones into ranges.
+// Because the indexes start at 1, 0 is ignored.
+func NewIndexSet(indexes ...uint) IndexSet {
+	sorted := append([]uint(nil), indexes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var set IndexSet
+	for _, index := range sorted {
+		if index == 0 {
+			continue
+		}
+
+		// index-1 instead of End+1, which overflows for the largest index
+		if n := len(set); n > 0 && index-1 <= set[n-1].End {
+			if index > set[n-1].End {
+				set[n-1].End = index
+			}
+			continue
+		}
+		set = append(set, IndexRange{Start: index, End: index})
+	}
+	return set
+}
+
+// ParseIndexSet parses a comma separated list of indexes and ranges like "1-5,8".
+// The indexes start at 1, so 0 is rejected.
+func ParseIndexSet(s string) (IndexSet, error) {
+	var set IndexSet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		start, end := part, part
+		if i := strings.IndexByte(part, '-'); i >= 0 {
+			start, end = part[:i], part[i+1:]
+		}
+
+		first, err := strconv.ParseUint(strings.TrimSpace(start), 10, 0)
+		if err != nil || first == 0 {
+			return nil, fmt.Errorf("invalid index range %q", part)
+		}
+		last, err := strconv.ParseUint(strings.TrimSpace(end), 10, 0)
+		if err != nil || last < first {
+			return nil, fmt.Errorf("invalid index range %q", part)
+		}
+
+		set = append(set, IndexRange{Start: uint(first), End: uint(last)})
+	}
+	return set, nil
+}
+
+// Contains reports whether the index is in the set.
+func (set IndexSet) Contains(index uint) bool {
+	for _, r := range set {
+		if index >= r.Start && index <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Indexes returns all indexes in the set in the order of the ranges.
+// Ranges whose Start is larger than End are empty.
+func (set IndexSet) Indexes() []uint {
+	var indexes []uint
+	for _, r := range set {
+		if r.Start > r.End {
+			continue
+		}
+
+		// index <= r.End is always true if End is the largest uint
+		for index := r.Start; ; index++ {
+			indexes = append(indexes, index)
+			if index == r.End {
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// String formats the set like "1-5,8".
+func (set IndexSet) String() string {
+	parts := make([]string, len(set))
+	for i, r := range set {
+		if r.Start == r.End {
+			parts[i] = strconv.FormatUint(uint64(r.Start), 10)
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r.Start, r.End)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarshalText formats the set using String.
+func (set IndexSet) MarshalText() ([]byte, error) {
+	return []byte(set.String()), nil
+}
+
+// UnmarshalText parses the set using ParseIndexSet.
+func (set *IndexSet) UnmarshalText(text []byte) error {
+	parsed, err := ParseIndexSet(string(text))
+	if err != nil {
+		return err
+	}
+
+	*set = parsed
+	return nil
+}
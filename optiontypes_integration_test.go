@@ -0,0 +1,32 @@
+package arigo_test
+
+import (
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOptionsRoundTrip(t *testing.T) {
+	_, client := newTestClient(t)
+
+	options := arigo.Options{
+		Header:     arigo.Headers{"X-A: 1", "X-B: 2"},
+		BTTracker:  arigo.TrackerList{"udp://a.example:80/announce", "http://b.example/announce"},
+		SelectFile: arigo.NewIndexSet(1, 2, 3, 7),
+	}
+	gid, err := client.AddURI([]string{"https://example.org/file"}, &options)
+	require.NoError(t, err)
+
+	values, err := gid.GetOptionValues()
+	require.NoError(t, err)
+	assert.Equal(t, "X-A: 1\nX-B: 2", values["header"])
+	assert.Equal(t, "1-3,7", values["select-file"])
+
+	decoded, err := gid.GetOptions()
+	require.NoError(t, err)
+	assert.Equal(t, options.Header, decoded.Header)
+	assert.Equal(t, options.BTTracker, decoded.BTTracker)
+	assert.Equal(t, options.SelectFile, decoded.SelectFile)
+}
@@ -0,0 +1,90 @@
+package arigo
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Add("X-B", "2")
+	h.Add("X-A", "1")
+	h.Add("X-A", "3")
+
+	headers := HeadersFromHTTP(h)
+	assert.Equal(t, Headers{"X-A: 1", "X-A: 3", "X-B: 2"}, headers)
+	assert.Equal(t, h, headers.HTTP())
+
+	var decoded Headers
+	require.NoError(t, json.Unmarshal([]byte(`"X-A: 1\nX-B: 2\n"`), &decoded))
+	assert.Equal(t, Headers{"X-A: 1", "X-B: 2"}, decoded)
+	require.NoError(t, json.Unmarshal([]byte(`["X-A: 1", "X-B: 2"]`), &decoded))
+	assert.Equal(t, Headers{"X-A: 1", "X-B: 2"}, decoded)
+}
+
+func TestIndexSet(t *testing.T) {
+	set, err := ParseIndexSet("1-5,8")
+	require.NoError(t, err)
+	assert.Equal(t, IndexSet{{Start: 1, End: 5}, {Start: 8, End: 8}}, set)
+	assert.Equal(t, "1-5,8", set.String())
+	assert.True(t, set.Contains(3))
+	assert.False(t, set.Contains(6))
+	assert.Equal(t, []uint{1, 2, 3, 4, 5, 8}, set.Indexes())
+
+	assert.Equal(t, set, NewIndexSet(8, 3, 1, 2, 4, 5, 3))
+
+	for _, s := range []string{"a", "5-1", "1-", "-2", "0", "0-3"} {
+		_, err := ParseIndexSet(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestIndexSetLimits(t *testing.T) {
+	max := ^uint(0)
+
+	set := NewIndexSet(0, max, max-1, 1)
+	assert.Equal(t, IndexSet{{Start: 1, End: 1}, {Start: max - 1, End: max}}, set)
+	assert.Equal(t, []uint{1, max - 1, max}, set.Indexes())
+	assert.False(t, set.Contains(0))
+
+	assert.Empty(t, IndexSet{{Start: 5, End: 1}}.Indexes())
+	assert.Nil(t, NewIndexSet(0))
+}
+
+func TestListOptions(t *testing.T) {
+	options := Options{
+		Header:           Headers{"X-A: 1", "X-B: 2"},
+		BTTracker:        TrackerList{"udp://a.example:80/announce", "http://b.example/announce"},
+		BTExcludeTracker: TrackerList{"*"},
+		SelectFile:       NewIndexSet(1, 2, 3, 7),
+	}
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"header": ["X-A: 1", "X-B: 2"],
+		"bt-tracker": "udp://a.example:80/announce,http://b.example/announce",
+		"bt-exclude-tracker": "*",
+		"select-file": "1-3,7"
+	}`, string(data))
+
+	values := options.Values()
+	assert.Equal(t, "X-A: 1\nX-B: 2", values["header"])
+	data, err = json.Marshal(values)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"header":["X-A: 1","X-B: 2"]`)
+
+	// GetOptions reports the headers as a single string
+	var decoded Options
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"header": "X-A: 1\nX-B: 2",
+		"bt-tracker": "udp://a.example:80/announce,http://b.example/announce",
+		"bt-exclude-tracker": "*",
+		"select-file": "1-3,7"
+	}`), &decoded))
+	assert.Equal(t, options, decoded)
+}
@@ -0,0 +1,210 @@
+package arigo
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// OptionSet is a set of options which can be passed to aria2.
+// It's implemented by Options, GlobalOptions and OptionValues.
+//
+// The fields of Options and GlobalOptions are omitted if they have
+// their zero value, so they can't set an option to false or zero.
+// Use OptionValues for that.
+type OptionSet interface {
+	// Values returns the options which are set.
+	Values() OptionValues
+}
+
+// OptionValues are options in the form aria2 uses on the wire: the names of
+// the options, like "allow-overwrite", mapped to their values as strings.
+// Options which aren't in the map are unset, so unlike Options, OptionValues
+// can tell an unset option apart from one which is explicitly false or zero.
+type OptionValues map[string]string
+
+// multiValueOptions are the options which can be set multiple times.
+// aria2 reports them as a single value with one entry per line,
+// but expects an array when they're set.
+var multiValueOptions = map[string]bool{
+	"header": true,
+}
+
+// MarshalJSON encodes the values like aria2 expects them.
+// Options which can be set multiple times are encoded as arrays of their lines.
+func (values OptionValues) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if multiValueOptions[name] {
+			lines := splitLines(value)
+			if lines == nil {
+				lines = []string{}
+			}
+			raw[name] = lines
+		} else {
+			raw[name] = value
+		}
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON decodes the values.
+// Arrays are joined with newlines, other values are converted to strings.
+func (values *OptionValues) UnmarshalJSON(data []byte) error {
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	*values = make(OptionValues, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case nil:
+		case []interface{}:
+			lines := make([]string, len(v))
+			for i, line := range v {
+				lines[i] = fmt.Sprint(line)
+			}
+			(*values)[name] = strings.Join(lines, "\n")
+		default:
+			(*values)[name] = fmt.Sprint(v)
+		}
+	}
+
+	return nil
+}
+
+// Values returns the values themselves.
+func (values OptionValues) Values() OptionValues {
+	return values
+}
+
+// Set sets the option to value and returns values to allow chaining.
+// Booleans, numbers, Headers and values implementing encoding.TextMarshaler,
+// like ByteSize and IndexSet, are formatted the way aria2 expects them,
+// other values using fmt.
+func (values OptionValues) Set(name string, value interface{}) OptionValues {
+	values[name] = formatOptionValue(value)
+	return values
+}
+
+func formatOptionValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case Headers:
+		return strings.Join(v, "\n")
+	case encoding.TextMarshaler:
+		if text, err := v.MarshalText(); err == nil {
+			return string(text)
+		}
+		return fmt.Sprint(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Merge sets all options of other and returns values to allow chaining.
+func (values OptionValues) Merge(other OptionSet) OptionValues {
+	for name, value := range optionValues(other) {
+		values[name] = value
+	}
+	return values
+}
+
+// Diff returns the options of values which aren't set to the same value in base.
+// Passing the diff to ChangeOptions changes the options from base to values
+// while leaving all other options alone.
+//
+// Options which are only set in base aren't part of the diff
+// because aria2 has no way to unset an option.
+func (values OptionValues) Diff(base OptionSet) OptionValues {
+	baseValues := optionValues(base)
+
+	diff := OptionValues{}
+	for name, value := range values {
+		if baseValue, ok := baseValues[name]; !ok || baseValue != value {
+			diff[name] = value
+		}
+	}
+
+	return diff
+}
+
+// Options decodes the values into Options.
+// Options which are explicitly false or zero become indistinguishable from unset ones.
+func (values OptionValues) Options() (Options, error) {
+	var options Options
+	err := values.decode(&options)
+	return options, err
+}
+
+// GlobalOptions decodes the values into GlobalOptions.
+// Options which are explicitly false or zero become indistinguishable from unset ones.
+func (values OptionValues) GlobalOptions() (GlobalOptions, error) {
+	var options GlobalOptions
+	err := values.decode(&options)
+	return options, err
+}
+
+func (values OptionValues) decode(v interface{}) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Values returns the options which are set, that is the ones which
+// don't have their zero value.
+func (options Options) Values() OptionValues {
+	return structValues(options)
+}
+
+// Values returns the options which are set, that is the ones which
+// don't have their zero value.
+func (options GlobalOptions) Values() OptionValues {
+	return structValues(options)
+}
+
+// structValues converts options using their JSON representation,
+// which encodes every value as a string like aria2 does.
+func structValues(options interface{}) OptionValues {
+	values := OptionValues{}
+	if data, err := json.Marshal(options); err == nil {
+		_ = json.Unmarshal(data, &values)
+	}
+	return values
+}
+
+// isNilOptionSet reports whether set is nil or a nil pointer, like a nil *Options.
+func isNilOptionSet(set OptionSet) bool {
+	if set == nil {
+		return true
+	}
+	v := reflect.ValueOf(set)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// optionValues returns the values of set, treating nil and nil pointers as empty.
+func optionValues(set OptionSet) OptionValues {
+	if isNilOptionSet(set) {
+		return OptionValues{}
+	}
+
+	values := set.Values()
+	if values == nil {
+		return OptionValues{}
+	}
+	return values
+}
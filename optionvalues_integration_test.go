@@ -0,0 +1,42 @@
+package arigo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionValues(t *testing.T) {
+	_, client := newTestClient(t)
+
+	gid, err := client.AddURI([]string{"https://example.org/file"}, arigo.OptionValues{}.
+		Set("allow-overwrite", true).
+		Set("max-download-limit", 1024))
+	require.NoError(t, err)
+
+	base, err := gid.GetOptionValues()
+	require.NoError(t, err)
+	assert.Equal(t, "true", base["allow-overwrite"])
+
+	target := arigo.OptionValues{}.Merge(base).
+		Set("allow-overwrite", false).
+		Set("max-download-limit", 0)
+	diff := target.Diff(base)
+	assert.Equal(t, arigo.OptionValues{"allow-overwrite": "false", "max-download-limit": "0"}, diff)
+	require.NoError(t, gid.ChangeOptions(diff))
+
+	values, err := gid.GetOptionValues()
+	require.NoError(t, err)
+	assert.Equal(t, target, values)
+
+	require.NoError(t, client.ChangeGlobalOptions(arigo.OptionValues{"max-overall-download-limit": "0"}))
+	global, err := client.GetGlobalOptionValues()
+	require.NoError(t, err)
+	assert.Equal(t, "0", global["max-overall-download-limit"])
+
+	err = client.ChangeGlobalOptions(arigo.OptionValues{"rpc-listen-port": "6801"})
+	assert.True(t, errors.Is(err, arigo.ErrOptionNotChangeable))
+}
@@ -0,0 +1,66 @@
+package arigo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionValuesSet(t *testing.T) {
+	values := OptionValues{}.
+		Set("allow-overwrite", false).
+		Set("max-download-limit", uint(0)).
+		Set("seed-ratio", float32(1.5)).
+		Set("dir", "/tmp")
+
+	assert.Equal(t, OptionValues{
+		"allow-overwrite":    "false",
+		"max-download-limit": "0",
+		"seed-ratio":         "1.5",
+		"dir":                "/tmp",
+	}, values)
+
+	data, err := json.Marshal(values)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"allow-overwrite": "false", "max-download-limit": "0", "seed-ratio": "1.5", "dir": "/tmp"}`, string(data))
+}
+
+func TestOptionValuesConversion(t *testing.T) {
+	options := Options{Dir: "/tmp", Split: 4, AllowOverwrite: true}
+	values := options.Values()
+	assert.Equal(t, OptionValues{"dir": "/tmp", "split": "4", "allow-overwrite": "true"}, values)
+
+	decoded, err := values.Options()
+	require.NoError(t, err)
+	assert.Equal(t, options, decoded)
+
+	global, err := OptionValues{"dir": "/tmp", "max-concurrent-downloads": "3"}.GlobalOptions()
+	require.NoError(t, err)
+	assert.Equal(t, GlobalOptions{Options: Options{Dir: "/tmp"}, MaxConcurrentDownloads: 3}, global)
+
+	assert.Equal(t, OptionValues{}, optionValues(nil))
+	assert.Equal(t, OptionValues{}, optionValues((*Options)(nil)))
+	assert.Equal(t, OptionValues{"split": "4"}, optionValues(&Options{Split: 4}))
+}
+
+func TestOptionValuesDiff(t *testing.T) {
+	base := OptionValues{"allow-overwrite": "true", "split": "4", "dir": "/tmp"}
+	target := base.Diff(nil).Merge(OptionValues{"allow-overwrite": "false", "max-tries": "3"})
+	delete(target, "dir")
+
+	assert.Equal(t, OptionValues{"allow-overwrite": "false", "max-tries": "3"}, target.Diff(base))
+	assert.Equal(t, OptionValues{}, base.Diff(base))
+	assert.Equal(t, OptionValues{"split": "5"}, OptionValues{"split": "5"}.Diff(Options{Split: 4}))
+}
+
+func TestAddParamsNilOptions(t *testing.T) {
+	params := []interface{}{"https://example.org"}
+
+	assert.Equal(t, params, addParams(params, QueueEndPosition, nil))
+	assert.Equal(t, params, addParams(params, QueueEndPosition, (*Options)(nil)))
+	assert.Equal(t, []interface{}{"https://example.org", uint(1)}, addParams(params, 1, (*Options)(nil)))
+	assert.Equal(t, []interface{}{"https://example.org", OptionValues{"split": "4"}},
+		addParams(params, QueueEndPosition, &Options{Split: 4}))
+}
@@ -0,0 +1,255 @@
+package arigo
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultProgressInterval is the interval at which progress is sampled
+	// if ProgressOptions.Interval is not set.
+	DefaultProgressInterval = time.Second
+
+	// DefaultSpeedWindow is the number of samples the speed is averaged over
+	// if ProgressOptions.SpeedWindow is not set.
+	DefaultSpeedWindow = 5
+
+	// UnknownETA is the ETA of a Progress when it can't be estimated.
+	UnknownETA time.Duration = -1
+)
+
+// progressKeys are the keys which are always requested when sampling progress.
+// downloadSpeed is used as the speed until there are enough samples to measure it.
+var progressKeys = []string{"gid", "status", "totalLength", "completedLength", "downloadSpeed"}
+
+// ProgressOptions configures how the progress of a download is watched.
+type ProgressOptions struct {
+	// Interval between two samples. If zero, DefaultProgressInterval is used.
+	Interval time.Duration
+
+	// Keys of the status to request in addition to the ones required to calculate
+	// the progress (gid, status, totalLength, completedLength and downloadSpeed).
+	// Use it to request things like connections or files.
+	// If Keys contains "all", the whole status is requested.
+	Keys []string
+
+	// SpeedWindow is the number of samples the speed is averaged over.
+	// If zero, DefaultSpeedWindow is used.
+	SpeedWindow int
+}
+
+func (opts ProgressOptions) keys() []string {
+	for _, key := range opts.Keys {
+		if key == "all" {
+			return nil
+		}
+	}
+
+	keys := append([]string{}, progressKeys...)
+	for _, key := range opts.Keys {
+		required := false
+		for _, k := range progressKeys {
+			if k == key {
+				required = true
+				break
+			}
+		}
+
+		if !required {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Progress is a snapshot of the progress of a download.
+type Progress struct {
+	Status Status    // Sampled status, only the requested keys are set
+	Time   time.Time // Time the status was sampled at
+
+	Percent float64       // Completed length in percent of the total length, 0 if the total length is unknown
+	Speed   float64       // Download speed in bytes/sec averaged over the last samples
+	ETA     time.Duration // Estimated time until the download completes or UnknownETA
+	Delta   uint          // Number of bytes completed since the previous snapshot
+}
+
+type progressSample struct {
+	time      time.Time
+	completed uint
+}
+
+// progressMeter calculates the progress from successive samples.
+type progressMeter struct {
+	window  int
+	samples []progressSample
+}
+
+func newProgressMeter(window int) *progressMeter {
+	if window <= 0 {
+		window = DefaultSpeedWindow
+	}
+	return &progressMeter{window: window}
+}
+
+// add adds a sample and returns the resulting progress.
+func (m *progressMeter) add(status Status, now time.Time) Progress {
+	p := Progress{
+		Status: status,
+		Time:   now,
+		ETA:    UnknownETA,
+	}
+
+	if status.TotalLength > 0 {
+		p.Percent = float64(status.CompletedLength) / float64(status.TotalLength) * 100
+	}
+
+	if n := len(m.samples); n > 0 && status.CompletedLength > m.samples[n-1].completed {
+		p.Delta = status.CompletedLength - m.samples[n-1].completed
+	}
+
+	// the window spans window samples, so window+1 of them are kept
+	m.samples = append(m.samples, progressSample{now, status.CompletedLength})
+	if len(m.samples) > m.window+1 {
+		m.samples = m.samples[len(m.samples)-m.window-1:]
+	}
+
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	if elapsed := last.time.Sub(first.time); elapsed > 0 && last.completed > first.completed {
+		p.Speed = float64(last.completed-first.completed) / elapsed.Seconds()
+	} else if len(m.samples) == 1 {
+		// aria2's own estimate is better than nothing
+		p.Speed = float64(status.DownloadSpeed)
+	}
+
+	switch {
+	case status.Status == StatusCompleted:
+		p.ETA = 0
+	case p.Speed > 0 && status.TotalLength >= status.CompletedLength && status.TotalLength > 0:
+		remaining := float64(status.TotalLength - status.CompletedLength)
+		p.ETA = time.Duration(remaining / p.Speed * float64(time.Second))
+	}
+
+	return p
+}
+
+// ProgressWatcher delivers the progress of a download over a channel.
+type ProgressWatcher struct {
+	// C delivers the progress snapshots.
+	// If the receiver doesn't keep up, older snapshots are discarded
+	// in favour of the latest one.
+	// It's closed when watching stops, the reason is reported by Err.
+	C <-chan Progress
+
+	c    chan Progress
+	done chan struct{}
+	err  error
+}
+
+// Err returns nil while the watcher is running.
+// Afterwards it returns nil if the download completed, a *DownloadError if
+// it failed, ErrDownloadStopped if it was removed, ctx.Err() if the context passed
+// to WatchProgress is done or the error of a failed TellStatus call.
+func (w *ProgressWatcher) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+func (w *ProgressWatcher) send(p Progress) {
+	for {
+		select {
+		case w.c <- p:
+			return
+		default:
+			// discard the stale snapshot
+			select {
+			case <-w.c:
+			default:
+			}
+		}
+	}
+}
+
+// WatchProgress samples the status of the download every opts.Interval
+// and delivers the progress over the channel of the returned watcher.
+// The first sample is taken immediately.
+//
+// Watching stops when the download completes, fails or is removed,
+// after delivering a final snapshot, or when ctx is done.
+func (gid *GID) WatchProgress(ctx context.Context, opts ProgressOptions) *ProgressWatcher {
+	c := make(chan Progress, 1)
+	w := &ProgressWatcher{
+		C:    c,
+		c:    c,
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		w.err = gid.client.watchProgress(ctx, gid.GID, opts, w.send)
+		close(w.done)
+		close(w.c)
+	}()
+
+	return w
+}
+
+// WatchProgressFunc is like WatchProgress but calls f with every snapshot instead.
+// It blocks until watching stops and returns the same error as ProgressWatcher.Err.
+func (gid *GID) WatchProgressFunc(ctx context.Context, opts ProgressOptions, f func(p Progress)) error {
+	return gid.client.watchProgress(ctx, gid.GID, opts, f)
+}
+
+// watchProgress samples the progress of the download and calls emit with every snapshot
+// until the download has stopped or ctx is done.
+func (c *Client) watchProgress(ctx context.Context, gid string, opts ProgressOptions, emit func(p Progress)) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	keys := opts.keys()
+	meter := newProgressMeter(opts.SpeedWindow)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the events cause an immediate final sample
+	var events <-chan Event
+	if c.pollInterval == 0 {
+		events = c.Events(ctx, EventFilter{
+			Types: finishEvents,
+			GIDs:  []string{gid},
+		}).C
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := c.TellStatusContext(ctx, gid, keys...)
+		if err != nil {
+			return err
+		}
+
+		emit(meter.add(status, time.Now()))
+
+		switch status.Status {
+		case StatusCompleted, StatusRemoved:
+			return statusError(status)
+		case StatusError:
+			// the sample might not contain the details of the error
+			return c.downloadError(ctx, gid)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-events:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
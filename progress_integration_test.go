@@ -0,0 +1,68 @@
+package arigo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/siku2/arigo"
+	"github.com/siku2/arigo/arigotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchProgress(t *testing.T) {
+	server, client := newTestClient(t)
+
+	gid, err := client.AddURI([]string{"https://example.org/file"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, server.Simulate(gid.GID, arigotest.Simulation{TotalLength: 400, Speed: 100}))
+
+	watcher := gid.WatchProgress(context.Background(), arigo.ProgressOptions{
+		Interval: time.Hour,
+		Keys:     []string{"downloadSpeed"},
+	})
+
+	first := <-watcher.C
+	assert.Equal(t, arigo.StatusWaiting, first.Status.Status)
+	assert.Equal(t, 0.0, first.Percent)
+	assert.Equal(t, arigo.UnknownETA, first.ETA)
+
+	// the completion event causes a final sample even though the interval is long
+	for i := 0; i < 4; i++ {
+		server.Advance(time.Second)
+	}
+
+	var last arigo.Progress
+	for p := range watcher.C {
+		last = p
+	}
+
+	assert.NoError(t, watcher.Err())
+	assert.Equal(t, arigo.StatusCompleted, last.Status.Status)
+	assert.Equal(t, 100.0, last.Percent)
+	assert.Equal(t, time.Duration(0), last.ETA)
+	assert.Equal(t, uint(400), last.Delta)
+}
+
+func TestWatchProgressFunc(t *testing.T) {
+	server, client := newTestClient(t)
+
+	gid, err := client.AddURI([]string{"https://example.org/file"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, server.Simulate(gid.GID, arigotest.Simulation{TotalLength: 400, Speed: 100, ExitStatus: arigo.NetworkError, FailAt: 200}))
+
+	var snapshots []arigo.Progress
+	err = gid.WatchProgressFunc(context.Background(), arigo.ProgressOptions{Interval: 10 * time.Millisecond}, func(p arigo.Progress) {
+		snapshots = append(snapshots, p)
+		server.Advance(time.Second)
+	})
+
+	var downloadErr *arigo.DownloadError
+	require.True(t, errors.As(err, &downloadErr))
+	assert.Equal(t, arigo.NetworkError, downloadErr.ExitStatus)
+	assert.True(t, downloadErr.IsRetryable())
+	require.NotEmpty(t, snapshots)
+	assert.Equal(t, 50.0, snapshots[len(snapshots)-1].Percent)
+}
@@ -0,0 +1,54 @@
+package arigo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressOptionsKeys(t *testing.T) {
+	assert.Equal(t, progressKeys, ProgressOptions{}.keys())
+	assert.Contains(t, ProgressOptions{}.keys(), "downloadSpeed")
+	assert.Equal(t, append(append([]string{}, progressKeys...), "connections"),
+		ProgressOptions{Keys: []string{"status", "connections"}}.keys())
+	assert.Nil(t, ProgressOptions{Keys: []string{"all"}}.keys())
+}
+
+func TestProgressMeter(t *testing.T) {
+	meter := newProgressMeter(2)
+	start := time.Unix(0, 0)
+
+	p := meter.add(Status{Status: StatusActive, TotalLength: 1000, DownloadSpeed: 50}, start)
+	assert.Equal(t, 0.0, p.Percent)
+	assert.Equal(t, 50.0, p.Speed)
+	assert.Equal(t, 20*time.Second, p.ETA)
+	assert.Equal(t, uint(0), p.Delta)
+
+	p = meter.add(Status{Status: StatusActive, TotalLength: 1000, CompletedLength: 100}, start.Add(time.Second))
+	assert.Equal(t, 10.0, p.Percent)
+	assert.Equal(t, 100.0, p.Speed)
+	assert.Equal(t, 9*time.Second, p.ETA)
+	assert.Equal(t, uint(100), p.Delta)
+
+	p = meter.add(Status{Status: StatusActive, TotalLength: 1000, CompletedLength: 400}, start.Add(2*time.Second))
+	assert.Equal(t, 200.0, p.Speed)
+	assert.Equal(t, 3*time.Second, p.ETA)
+	assert.Equal(t, uint(300), p.Delta)
+
+	// the first sample is outside of the window now
+	p = meter.add(Status{Status: StatusActive, TotalLength: 1000, CompletedLength: 500}, start.Add(3*time.Second))
+	assert.Equal(t, 200.0, p.Speed)
+	assert.Equal(t, uint(100), p.Delta)
+
+	// stalled downloads have no ETA
+	meter = newProgressMeter(1)
+	meter.add(Status{Status: StatusActive, TotalLength: 1000}, start)
+	p = meter.add(Status{Status: StatusActive, TotalLength: 1000}, start.Add(time.Second))
+	assert.Equal(t, 0.0, p.Speed)
+	assert.Equal(t, UnknownETA, p.ETA)
+
+	p = meter.add(Status{Status: StatusCompleted, TotalLength: 1000, CompletedLength: 1000}, start.Add(2*time.Second))
+	assert.Equal(t, 100.0, p.Percent)
+	assert.Equal(t, time.Duration(0), p.ETA)
+}
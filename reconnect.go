@@ -0,0 +1,262 @@
+package arigo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/rpc2"
+)
+
+// Backoff determines the delay between reconnection attempts.
+// The delay starts at Initial and is multiplied by Factor after every
+// failed attempt until it reaches Max.
+// Fields which are zero are taken from DefaultBackoff,
+// a Factor below 1 is treated as 1.
+type Backoff struct {
+	Initial time.Duration // Delay before the first attempt
+	Max     time.Duration // Upper limit of the delay
+	Factor  float64       // Multiplier applied after every failed attempt
+}
+
+// DefaultBackoff provides the fields of a Backoff which aren't set.
+var DefaultBackoff = Backoff{
+	Initial: 500 * time.Millisecond,
+	Max:     30 * time.Second,
+	Factor:  2,
+}
+
+// withDefaults fills the fields which aren't set from DefaultBackoff.
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = DefaultBackoff.Max
+	}
+	if b.Factor == 0 {
+		b.Factor = DefaultBackoff.Factor
+	}
+	if b.Factor < 1 {
+		b.Factor = 1
+	}
+	return b
+}
+
+// delay returns the delay before the given attempt, starting at 0.
+func (b Backoff) delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Factor
+	}
+
+	if d > float64(b.Max) {
+		return b.Max
+	}
+	return time.Duration(d)
+}
+
+type dialFunc func(ctx context.Context) (*rpc2.Client, func() error, error)
+
+// reconcileTimeout limits each status request made by reconcileWaiters.
+const reconcileTimeout = 10 * time.Second
+
+type reconnector struct {
+	dial    dialFunc
+	backoff Backoff
+
+	err error // guarded by the mutex of the client
+}
+
+// DialReconnecting creates a new connection to an aria2 rpc interface which
+// is automatically reestablished when it's lost, for example because aria2 was restarted.
+//
+// Redialling is attempted according to backoff until it succeeds or the client is closed.
+// Calls made while the client is disconnected fail.
+// opts are used for every connection attempt.
+//
+// Events sent by aria2 while the client is disconnected are lost.
+// To make sure that waiting for a download doesn't block forever,
+// the status of every download which is waited for is checked after reconnecting
+// and the corresponding CompleteEvent, ErrorEvent or StopEvent is dispatched
+// if it finished in the meantime. These events are also delivered to event streams.
+//
+// Use ReconnectErr to find out why the connection was lost or why
+// reconnecting doesn't succeed.
+func DialReconnecting(ctx context.Context, url string, authToken string, backoff Backoff, opts ...DialOption) (*Client, error) {
+	backoff = backoff.withDefaults()
+
+	dialOpts := newDialOptions(opts)
+	events := newEventBus(true)
+	dial := func(ctx context.Context) (*rpc2.Client, func() error, error) {
+		return dialWebSocket(ctx, url, dialOpts, events)
+	}
+
+	rpcClient, transportErr, err := dial(ctx)
+	if err != nil {
+		events.close(err)
+		return nil, err
+	}
+
+	client := newClient(rpcClient, authToken, events)
+	client.transportErr = transportErr
+	client.contextCalls = true
+	client.reconnect = &reconnector{dial: dial, backoff: backoff}
+	dialOpts.apply(client)
+	go client.runReconnecting()
+
+	return client, nil
+}
+
+// runReconnecting runs the rpc client and replaces it with a new one
+// whenever the connection is lost.
+func (c *Client) runReconnecting() {
+	for {
+		c.setReconnectErr(c.runRPC())
+
+		rpcClient, transportErr, ok := c.redial()
+		if !ok || !c.replaceRPC(rpcClient, transportErr) {
+			c.finish(ErrClientClosed)
+			return
+		}
+
+		go c.reconcileWaiters()
+	}
+}
+
+// redial dials until a new connection is established.
+// It returns false if the client was closed in the meantime.
+func (c *Client) redial() (*rpc2.Client, func() error, bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-time.After(c.reconnect.backoff.delay(attempt)):
+		case <-ctx.Done():
+			return nil, nil, false
+		}
+
+		rpcClient, transportErr, err := c.reconnect.dial(ctx)
+		if err == nil {
+			c.registerHandlers(rpcClient)
+			return rpcClient, transportErr, true
+		}
+		if ctx.Err() == nil {
+			c.setReconnectErr(err)
+		}
+	}
+}
+
+func (c *Client) setReconnectErr(err error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.reconnect.err = err
+}
+
+// ReconnectErr returns why the last connection attempt of a client created by
+// DialReconnecting failed or, if that attempt succeeded, why the connection
+// before it was lost. It returns nil if the connection was never lost
+// and for clients which don't reconnect.
+func (c *Client) ReconnectErr() error {
+	if c.reconnect == nil {
+		return nil
+	}
+
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	return c.reconnect.err
+}
+
+// replaceRPC replaces the current rpc client.
+// It returns false and closes rpcClient if the client was already closed.
+func (c *Client) replaceRPC(rpcClient *rpc2.Client, transportErr func() error) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.closed {
+		_ = rpcClient.Close()
+		return false
+	}
+
+	c.rpcClient = rpcClient
+	c.transportErr = transportErr
+	c.resetCapabilities()
+	return true
+}
+
+// reconcileWaiters checks the status of all downloads which are waited for
+// and dispatches the event for the ones that have finished.
+// Downloads whose status can't be retrieved are skipped.
+func (c *Client) reconcileWaiters() {
+	for _, gid := range c.waiters.gids() {
+		ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+		status, err := c.TellStatusContext(ctx, gid, "gid", "status")
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		var evtType EventType
+		switch status.Status {
+		case StatusCompleted:
+			evtType = CompleteEvent
+		case StatusError:
+			evtType = ErrorEvent
+		case StatusRemoved:
+			evtType = StopEvent
+		default:
+			continue
+		}
+
+		c.events.dispatch(evtType, &DownloadEvent{GID: gid})
+	}
+}
+
+// waiterSet keeps track of the downloads which are waited for.
+type waiterSet struct {
+	counts map[string]uint
+	mut    sync.Mutex
+}
+
+func (s *waiterSet) add(gid string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.counts == nil {
+		s.counts = make(map[string]uint)
+	}
+	s.counts[gid]++
+}
+
+func (s *waiterSet) remove(gid string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.counts[gid] <= 1 {
+		delete(s.counts, gid)
+	} else {
+		s.counts[gid]--
+	}
+}
+
+func (s *waiterSet) gids() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	gids := make([]string, 0, len(s.counts))
+	for gid := range s.counts {
+		gids = append(gids, gid)
+	}
+	return gids
+}
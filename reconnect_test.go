@@ -0,0 +1,98 @@
+package arigo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 5 * time.Second, Factor: 2}
+
+	assert.Equal(t, time.Second, b.delay(0))
+	assert.Equal(t, 2*time.Second, b.delay(1))
+	assert.Equal(t, 4*time.Second, b.delay(2))
+	assert.Equal(t, 5*time.Second, b.delay(3))
+	assert.Equal(t, 5*time.Second, b.delay(100))
+}
+
+func TestBackoffDefaults(t *testing.T) {
+	assert.Equal(t, DefaultBackoff, Backoff{}.withDefaults())
+
+	b := Backoff{Initial: time.Second}.withDefaults()
+	assert.Equal(t, Backoff{Initial: time.Second, Max: DefaultBackoff.Max, Factor: DefaultBackoff.Factor}, b)
+	assert.Equal(t, 2*time.Second, b.delay(1))
+	assert.Equal(t, DefaultBackoff.Max, b.delay(100))
+
+	b = Backoff{Initial: time.Second, Max: 10 * time.Second, Factor: 0.5}.withDefaults()
+	assert.Equal(t, 1.0, b.Factor)
+	assert.Equal(t, time.Second, b.delay(5))
+}
+
+func TestDialReconnecting(t *testing.T) {
+	const gid = "2089b05ecca3d829"
+
+	upgrader := websocket.Upgrader{}
+	conns := make(chan *websocket.Conn, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		conns <- ws
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	client, err := DialReconnecting(context.Background(), url, "", Backoff{Initial: time.Millisecond, Max: time.Millisecond, Factor: 1})
+	require.NoError(t, err)
+	defer client.Close()
+
+	first := <-conns
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.WaitForDownload(gid)
+	}()
+
+	for len(client.waiters.gids()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.NoError(t, client.ReconnectErr())
+
+	// the download completes while the connection is down
+	require.NoError(t, first.Close())
+
+	second := <-conns
+	defer second.Close()
+
+	assert.Error(t, client.ReconnectErr())
+
+	var req struct {
+		ID     uint64        `json:"id"`
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}
+	require.NoError(t, second.ReadJSON(&req))
+	assert.Equal(t, "aria2.tellStatus", req.Method)
+	assert.Equal(t, gid, req.Params[0])
+
+	require.NoError(t, second.WriteJSON(map[string]interface{}{
+		"id":     req.ID,
+		"result": map[string]interface{}{"gid": gid, "status": StatusCompleted},
+	}))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("WaitForDownload didn't return after reconnecting")
+	}
+}
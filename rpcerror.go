@@ -0,0 +1,134 @@
+package arigo
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/cenkalti/rpc2"
+	"github.com/siku2/arigo/internal/pkg/jsonrpc"
+)
+
+var (
+	// ErrGIDNotFound is matched by the errors of calls referring to a download
+	// which doesn't exist, or which isn't in the queue the method operates on,
+	// like changing the position of a download which isn't waiting.
+	// Calls rejected because the download is in the wrong state,
+	// like pausing a download which is already paused, don't match it.
+	ErrGIDNotFound = errors.New("GID not found")
+	// ErrUnauthorized is matched by the errors of calls which were rejected
+	// because the secret token is wrong.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrInvalidParams is matched by the errors of calls which were rejected
+	// because of a missing or malformed parameter.
+	ErrInvalidParams = errors.New("invalid params")
+	// ErrMethodNotFound is matched by the errors of calls to a method
+	// which aria2 doesn't know about.
+	ErrMethodNotFound = errors.New("method not found")
+)
+
+// Codes of the JSON-RPC errors which aren't specific to a method.
+// aria2 uses code 1 for all errors of its methods.
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// errorMessage is a message aria2 uses for a kind of error.
+// A GID, index or option name may come between prefix and suffix.
+type errorMessage struct {
+	prefix string
+	suffix string
+}
+
+func (m errorMessage) matches(message string) bool {
+	return len(message) >= len(m.prefix)+len(m.suffix) &&
+		strings.HasPrefix(message, m.prefix) &&
+		strings.HasSuffix(message, m.suffix)
+}
+
+// The messages aria2 uses for the different kinds of errors.
+var (
+	gidNotFoundMessages = []errorMessage{
+		{"GID ", " is not found"},
+		{"The prefix GID ", " is not found."},
+		{"Active Download not found for GID#", ""},
+		{"No such download for GID#", ""},
+		{"GID#", " not found in the waiting queue."},
+	}
+	invalidParamsMessages = []errorMessage{
+		{"Bad GID ", ""},
+		{"The prefix GID ", " is not unique."},
+		{"The parameter at ", " has wrong type."},
+		{"The parameter at ", " is required but missing."},
+		{"GID is not provided.", ""},
+		{"URI is not provided.", ""},
+		{"Torrent data is not provided.", ""},
+		{"Metalink data is not provided.", ""},
+		{"Illegal argument.", ""},
+		{"Invalid argument", ""},
+		{"We encountered a problem while processing the option '--", ""},
+	}
+)
+
+// RPCError is the error of a call which aria2 rejected.
+// Depending on the reason, it matches ErrGIDNotFound, ErrUnauthorized,
+// ErrInvalidParams or ErrMethodNotFound using errors.Is.
+type RPCError struct {
+	Code    int    // JSON-RPC error code
+	Message string // Human readable message
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is the sentinel error matching the reason of e.
+func (e *RPCError) Is(target error) bool {
+	return target != nil && target == classifyError(e.Code, e.Message)
+}
+
+// classifyError returns the sentinel error matching an error reported by aria2
+// or nil if the error doesn't match any of them.
+func classifyError(code int, message string) error {
+	switch code {
+	case codeMethodNotFound:
+		return ErrMethodNotFound
+	case codeInvalidParams:
+		return ErrInvalidParams
+	}
+
+	if message == "Unauthorized" {
+		return ErrUnauthorized
+	}
+
+	for _, m := range gidNotFoundMessages {
+		if m.matches(message) {
+			return ErrGIDNotFound
+		}
+	}
+
+	for _, m := range invalidParamsMessages {
+		if m.matches(message) {
+			return ErrInvalidParams
+		}
+	}
+
+	return nil
+}
+
+// callError converts the error of a rpc2 call.
+// Errors reported by aria2 are converted to an *RPCError,
+// other errors are returned unchanged.
+func callError(err error) error {
+	serverErr, ok := err.(rpc2.ServerError)
+	if !ok {
+		return err
+	}
+
+	message, rpcErr := jsonrpc.DecodeError(string(serverErr))
+	if rpcErr == nil {
+		return rpc2.ServerError(message)
+	}
+
+	return &RPCError{Code: rpcErr.Code, Message: rpcErr.Message}
+}
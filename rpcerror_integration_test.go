@@ -0,0 +1,40 @@
+package arigo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCError(t *testing.T) {
+	server, client := newTestClient(t)
+
+	_, err := client.TellStatus("0000000000000001")
+	var rpcErr *arigo.RPCError
+	require.True(t, errors.As(err, &rpcErr), "%T", err)
+	assert.Equal(t, 1, rpcErr.Code)
+	assert.Equal(t, "GID 0000000000000001 is not found", rpcErr.Message)
+	assert.True(t, errors.Is(err, arigo.ErrGIDNotFound))
+	assert.False(t, errors.Is(err, arigo.ErrUnauthorized))
+
+	// the connection survives errors
+	_, err = client.GetVersion()
+	assert.NoError(t, err)
+
+	unauthorized, err := arigo.Dial(server.URL, "wrong")
+	require.NoError(t, err)
+	defer unauthorized.Close()
+
+	_, err = unauthorized.GetVersion()
+	assert.True(t, errors.Is(err, arigo.ErrUnauthorized))
+
+	httpClient, err := arigo.DialHTTP(server.HTTPURL, server.Secret)
+	require.NoError(t, err)
+	defer httpClient.Close()
+
+	err = httpClient.Pause("0000000000000001")
+	assert.True(t, errors.Is(err, arigo.ErrGIDNotFound))
+}
@@ -0,0 +1,51 @@
+package arigo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cenkalti/rpc2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	assert.Equal(t, ErrUnauthorized, classifyError(1, "Unauthorized"))
+	assert.Equal(t, ErrGIDNotFound, classifyError(1, "GID 2089b05ecca3d829 is not found"))
+	assert.Equal(t, ErrGIDNotFound, classifyError(1, "Active Download not found for GID#2089b05ecca3d829"))
+	assert.Equal(t, ErrGIDNotFound, classifyError(1, "No such download for GID#2089b05ecca3d829"))
+	assert.Equal(t, ErrInvalidParams, classifyError(1, "Bad GID 2089b05"))
+	assert.Equal(t, ErrGIDNotFound, classifyError(1, "GID#2089b05ecca3d829 not found in the waiting queue."))
+	assert.Equal(t, ErrInvalidParams, classifyError(1, "The parameter at 1 has wrong type."))
+	assert.Equal(t, ErrInvalidParams, classifyError(1, "The parameter at 0 is required but missing."))
+	assert.Equal(t, ErrInvalidParams, classifyError(1, "The prefix GID 2089 is not unique."))
+	assert.Equal(t, ErrInvalidParams, classifyError(1, "Invalid argument"))
+	assert.Equal(t, ErrInvalidParams, classifyError(1, "We encountered a problem while processing the option '--split'."))
+	assert.Equal(t, ErrInvalidParams, classifyError(-32602, "Invalid params."))
+	assert.Equal(t, ErrMethodNotFound, classifyError(-32601, "Method not found."))
+	assert.Nil(t, classifyError(1, "GID#2089b05ecca3d829 cannot be paused now"))
+}
+
+func TestClassifyErrorUnrelated(t *testing.T) {
+	// messages which merely contain parts of the ones above
+	assert.Nil(t, classifyError(1, "Resource /GID 1 is not found"))
+	assert.Nil(t, classifyError(1, "fileIndex is out of range"))
+	assert.Nil(t, classifyError(1, "max-concurrent-downloads must be between 1 and 100"))
+	assert.Nil(t, classifyError(1, "No URI to download. Download aborted: Bad GID"))
+	assert.Nil(t, classifyError(1, "The response status is not successful. status=404, Invalid argument"))
+	assert.Nil(t, classifyError(1, "GID "))
+}
+
+func TestCallError(t *testing.T) {
+	err := callError(rpc2.ServerError(`{"code":1,"message":"Unauthorized"}`))
+	assert.Equal(t, &RPCError{Code: 1, Message: "Unauthorized"}, err)
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	assert.False(t, errors.Is(err, ErrGIDNotFound))
+
+	err = callError(rpc2.ServerError(`"connection refused"`))
+	assert.Equal(t, rpc2.ServerError("connection refused"), err)
+
+	err = callError(rpc2.ServerError("connection refused"))
+	assert.Equal(t, rpc2.ServerError("connection refused"), err)
+
+	assert.Equal(t, ErrClientClosed, callError(ErrClientClosed))
+}
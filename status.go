@@ -18,7 +18,7 @@ const (
 	// StatusError represents downloads that were stopped because of error
 	StatusError DownloadStatus = "error"
 	// StatusCompleted represents stopped and completed downloads
-	StatusCompleted DownloadStatus = "completed"
+	StatusCompleted DownloadStatus = "complete"
 	// StatusRemoved represents the downloads removed by user
 	StatusRemoved DownloadStatus = "removed"
 )
@@ -37,9 +37,9 @@ type Status struct {
 	// Any overflow bits at the end are set to zero.
 	// When the download was not started yet, this will be an empty string.
 	BitField      string `json:"bitfield"`
-	DownloadSpeed uint   `json:"downloadSpeed,string"` // Download speed of this download measured in bytes/sec
-	UploadSpeed   uint   `json:"uploadSpeed,string"`   // Upload speed of this download measured in bytes/sec
-	InfoHash      string `json:"infoHash"`             // InfoHash. BitTorrent only
+	DownloadSpeed Rate   `json:"downloadSpeed"` // Download speed of this download measured in bytes/sec
+	UploadSpeed   Rate   `json:"uploadSpeed"`   // Upload speed of this download measured in bytes/sec
+	InfoHash      string `json:"infoHash"`      // InfoHash. BitTorrent only
 
 	// The number of seeders aria2 has connected to. BitTorrent only
 	NumSeeders uint `json:"numSeeders,string"`
@@ -57,3 +57,12 @@ func TestStatusFormat(t *testing.T) {
 	assert.EqualValues(t, true, file1.Selected)
 	assert.Equal(t, []URI{{Status: URIUsed, URI: "http://example.org/file"}}, file1.URIs)
 }
+
+func TestDownloadStatusValues(t *testing.T) {
+	// the values reported by aria2 in tellStatus
+	for _, s := range []string{"active", "waiting", "paused", "error", "complete", "removed"} {
+		var status DownloadStatus
+		assert.NoError(t, json.Unmarshal([]byte(`"`+s+`"`), &status))
+		assert.Contains(t, []DownloadStatus{StatusActive, StatusWaiting, StatusPaused, StatusError, StatusCompleted, StatusRemoved}, status, s)
+	}
+}
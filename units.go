@@ -0,0 +1,133 @@
+package arigo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes.
+// It's encoded as a plain number like aria2 reports sizes
+// and decodes values with K, M and G suffixes, like "1M" for 1048576.
+type ByteSize uint64
+
+// Units of ByteSize. aria2 uses binary units, so 1K is 1024 bytes.
+const (
+	Byte     ByteSize = 1
+	Kibibyte          = 1024 * Byte
+	Mebibyte          = 1024 * Kibibyte
+	Gibibyte          = 1024 * Mebibyte
+)
+
+var byteSizeSuffixes = []struct {
+	suffix string
+	unit   ByteSize
+}{
+	{"G", Gibibyte},
+	{"M", Mebibyte},
+	{"K", Kibibyte},
+}
+
+// ParseByteSize parses a size like "20K", "1M" or "0".
+// The suffixes K, M and G, which may also be lowercase, are multiples of 1024.
+func ParseByteSize(s string) (ByteSize, error) {
+	num, unit := strings.TrimSpace(s), Byte
+	for _, suffix := range byteSizeSuffixes {
+		if trimmed := strings.TrimSuffix(strings.ToUpper(num), suffix.suffix); len(trimmed) < len(num) {
+			num, unit = trimmed, suffix.unit
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(num, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if n > uint64(^ByteSize(0)/unit) {
+		return 0, fmt.Errorf("size %q out of range", s)
+	}
+
+	return ByteSize(n) * unit, nil
+}
+
+// String formats the size for humans, for example "1.5 MiB".
+func (size ByteSize) String() string {
+	return formatUnits(uint64(size), "")
+}
+
+// Compact formats the size using the K and M suffixes where possible,
+// for example "1M" for 1048576 and "1500" for 1500.
+// The G suffix isn't used because aria2 doesn't accept it for all options.
+func (size ByteSize) Compact() string {
+	for _, suffix := range byteSizeSuffixes[1:] {
+		if size >= suffix.unit && size%suffix.unit == 0 {
+			return strconv.FormatUint(uint64(size/suffix.unit), 10) + suffix.suffix
+		}
+	}
+
+	return strconv.FormatUint(uint64(size), 10)
+}
+
+// MarshalText encodes the size as a plain number of bytes.
+func (size ByteSize) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(size), 10)), nil
+}
+
+// UnmarshalText decodes a size using ParseByteSize.
+func (size *ByteSize) UnmarshalText(text []byte) error {
+	parsed, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+
+	*size = parsed
+	return nil
+}
+
+// Rate is a speed in bytes per second.
+// It's encoded and decoded like ByteSize, so "1M" is 1 MiB/s.
+type Rate uint64
+
+// ParseRate parses a speed like "20K", "1M" or "0", see ParseByteSize.
+func ParseRate(s string) (Rate, error) {
+	size, err := ParseByteSize(s)
+	return Rate(size), err
+}
+
+// String formats the speed for humans, for example "1.5 MiB/s".
+func (rate Rate) String() string {
+	return formatUnits(uint64(rate), "/s")
+}
+
+// Compact formats the speed using the K and M suffixes, see ByteSize.Compact.
+func (rate Rate) Compact() string {
+	return ByteSize(rate).Compact()
+}
+
+// MarshalText encodes the speed as a plain number of bytes per second.
+func (rate Rate) MarshalText() ([]byte, error) {
+	return ByteSize(rate).MarshalText()
+}
+
+// UnmarshalText decodes a speed using ParseRate.
+func (rate *Rate) UnmarshalText(text []byte) error {
+	return (*ByteSize)(rate).UnmarshalText(text)
+}
+
+// formatUnits formats n bytes with the largest binary unit
+// which keeps the value at 1 or above, rounded to one decimal.
+func formatUnits(n uint64, suffix string) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+	value, i := float64(n), 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return strconv.FormatUint(n, 10) + " B" + suffix
+	}
+	formatted := strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0")
+	return formatted + " " + units[i] + suffix
+}
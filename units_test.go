@@ -0,0 +1,66 @@
+package arigo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]ByteSize{
+		"0":    0,
+		"1500": 1500,
+		"20K":  20 * Kibibyte,
+		"20k":  20 * Kibibyte,
+		"1M":   Mebibyte,
+		"2G":   2 * Gibibyte,
+	}
+	for s, expected := range tests {
+		size, err := ParseByteSize(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+
+	for _, s := range []string{"", "M", "1.5M", "-1", "1T", "99999999999G"} {
+		_, err := ParseByteSize(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestByteSizeFormat(t *testing.T) {
+	assert.Equal(t, "1M", Mebibyte.Compact())
+	assert.Equal(t, "1536K", (1536 * Kibibyte).Compact())
+	assert.Equal(t, "1500", ByteSize(1500).Compact())
+	assert.Equal(t, "0", ByteSize(0).Compact())
+
+	assert.Equal(t, "512 B", ByteSize(512).String())
+	assert.Equal(t, "1 KiB", Kibibyte.String())
+	assert.Equal(t, "1.5 MiB", (1536 * Kibibyte).String())
+	assert.Equal(t, "2 GiB", (2 * Gibibyte).String())
+	assert.Equal(t, "1.5 MiB/s", Rate(1536*Kibibyte).String())
+}
+
+func TestSizeOptions(t *testing.T) {
+	var options Options
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"max-download-limit": "1M",
+		"min-split-size": "20971520",
+		"piece-length": "1M",
+		"lowest-speed-limit": "0"
+	}`), &options))
+
+	assert.Equal(t, Options{
+		MaxDownloadLimit: Rate(Mebibyte),
+		MinSplitSize:     20 * Mebibyte,
+		PieceLength:      Mebibyte,
+	}, options)
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"max-download-limit": "1048576", "min-split-size": "20971520", "piece-length": "1048576"}`, string(data))
+
+	assert.Equal(t, OptionValues{"max-download-limit": "1M"}, OptionValues{}.Set("max-download-limit", Rate(Mebibyte).Compact()))
+	assert.Equal(t, OptionValues{"max-download-limit": "1048576"}, OptionValues{}.Set("max-download-limit", Rate(Mebibyte)))
+}
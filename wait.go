@@ -0,0 +1,244 @@
+package arigo
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoDownloads is returned by WaitAny when no GIDs are given.
+var ErrNoDownloads = errors.New("no downloads to wait for")
+
+// finishEvents are the events which are dispatched when a download finishes.
+var finishEvents = []EventType{CompleteEvent, ErrorEvent, StopEvent}
+
+// DownloadResult is the outcome of a download which was waited for.
+type DownloadResult struct {
+	GID string
+
+	// Err is nil if the download completed, a *DownloadError if it failed
+	// and ErrDownloadStopped if it was removed.
+	// If aria2 rejected the request for the status of the download, Err is
+	// the error of the request, for example one matching ErrGIDNotFound if
+	// aria2 has already forgotten the download.
+	Err error
+
+	ErrorCode    ExitStatus // Code of the error if the download failed
+	ErrorMessage string     // Human readable message of the error if the download failed
+}
+
+// resultKeys are the keys requested to determine the result of a download.
+var resultKeys = []string{"gid", "status", "errorCode", "errorMessage", "files"}
+
+// downloadResult returns the result of the download.
+// It returns false if the download hasn't finished yet.
+func downloadResult(status Status) (DownloadResult, bool) {
+	switch status.Status {
+	case StatusCompleted, StatusError, StatusRemoved:
+	default:
+		return DownloadResult{}, false
+	}
+
+	result := DownloadResult{GID: status.GID, Err: statusError(status)}
+	if status.Status == StatusError {
+		result.ErrorCode = status.ErrorCode
+		result.ErrorMessage = status.ErrorMessage
+	}
+
+	return result, true
+}
+
+// WaitAll waits for all downloads denoted by gids to finish.
+// A download which failed or was removed doesn't stop the others from being waited for.
+//
+// It returns the result of every download in the order of gids.
+// The returned error is only set if waiting itself failed because ctx is done
+// or the connection to aria2 failed, in which case the results are nil.
+func (c *Client) WaitAll(ctx context.Context, gids ...string) ([]DownloadResult, error) {
+	results, err := c.waitFor(ctx, gids, len(gids))
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]DownloadResult, len(gids))
+	for i, gid := range gids {
+		ordered[i] = results[gid]
+	}
+
+	return ordered, nil
+}
+
+// WaitAny waits for any of the downloads denoted by gids to finish
+// and returns its result.
+// The returned error is only set if waiting itself failed,
+// like it is for WaitAll.
+func (c *Client) WaitAny(ctx context.Context, gids ...string) (DownloadResult, error) {
+	if len(gids) == 0 {
+		return DownloadResult{}, ErrNoDownloads
+	}
+
+	results, err := c.waitFor(ctx, gids, 1)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+
+	// if multiple downloads finished at once, prefer the first one
+	var result DownloadResult
+	for i := len(gids) - 1; i >= 0; i-- {
+		if r, ok := results[gids[i]]; ok {
+			result = r
+		}
+	}
+
+	return result, nil
+}
+
+// waitFor waits until n of the downloads have finished.
+// All downloads share a single event stream.
+func (c *Client) waitFor(ctx context.Context, gids []string, n int) (map[string]DownloadResult, error) {
+	results := make(map[string]DownloadResult, len(gids))
+
+	pending := make(map[string]bool, len(gids))
+	for _, gid := range gids {
+		pending[gid] = true
+	}
+	if n > len(pending) {
+		n = len(pending)
+	}
+
+	var events <-chan Event
+	var stream *EventStream
+	var ticks <-chan time.Time
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The stream is closed instead of blocking the events of all other
+	// streams if the events aren't read fast enough.
+	// It's then replaced and all downloads are checked again.
+	subscribe := func() {
+		stream = c.Events(streamCtx, EventFilter{
+			Types:      finishEvents,
+			GIDs:       gids,
+			BufferSize: len(pending),
+			Overflow:   OverflowError,
+		})
+		events = stream.C
+	}
+
+	if c.pollInterval > 0 {
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	} else {
+		// subscribe before checking the downloads so no event is missed
+		subscribe()
+	}
+
+	// register as waiters so the downloads are checked after reconnecting
+	for gid := range pending {
+		c.waiters.add(gid)
+	}
+	defer func() {
+		for gid := range pending {
+			c.waiters.remove(gid)
+		}
+	}()
+
+	finish := func(result DownloadResult) {
+		delete(pending, result.GID)
+		c.waiters.remove(result.GID)
+		results[result.GID] = result
+	}
+
+	// check finishes the download if it has finished.
+	// If aria2 rejects the request, the download is finished with its error.
+	check := func(gid string) error {
+		status, err := c.TellStatusContext(ctx, gid, resultKeys...)
+		if isRejected(err) {
+			finish(DownloadResult{GID: gid, Err: err})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if result, ok := downloadResult(status); ok {
+			finish(result)
+		}
+		return nil
+	}
+
+	checkPending := func() error {
+		gids := make([]string, 0, len(pending))
+		for gid := range pending {
+			gids = append(gids, gid)
+		}
+
+		for _, gid := range gids {
+			if err := check(gid); err != nil {
+				return err
+			}
+			if len(results) >= n {
+				break
+			}
+		}
+		return nil
+	}
+
+	if err := checkPending(); err != nil {
+		return nil, err
+	}
+
+	for len(results) < n {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if err := stream.Err(); !errors.Is(err, ErrEventOverflow) {
+					return nil, err
+				}
+
+				subscribe()
+				if err := checkPending(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if !pending[evt.GID] {
+				continue
+			}
+
+			switch evt.Type {
+			case CompleteEvent:
+				finish(DownloadResult{GID: evt.GID})
+			case StopEvent:
+				finish(DownloadResult{GID: evt.GID, Err: ErrDownloadStopped})
+			default:
+				// the status contains the error code
+				if err := check(evt.GID); err != nil {
+					return nil, err
+				}
+			}
+		case <-ticks:
+			if err := checkPending(); err != nil {
+				return nil, err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
+
+// isRejected reports whether err was reported by aria2 for a call it rejected,
+// as opposed to an error of the context or the connection.
+func isRejected(err error) bool {
+	var rpcErr *RPCError
+	var callErr *MethodCallError
+	return errors.As(err, &rpcErr) || errors.As(err, &callErr)
+}
@@ -0,0 +1,115 @@
+package arigo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/siku2/arigo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitAll(t *testing.T) {
+	server, client := newTestClient(t)
+
+	var gids []string
+	for i := 0; i < 20; i++ {
+		gid, err := client.AddURI([]string{"https://example.org"}, nil)
+		require.NoError(t, err)
+		gids = append(gids, gid.GID)
+	}
+
+	// finished before waiting
+	require.NoError(t, server.CompleteDownload(gids[0]))
+
+	done := make(chan []arigo.DownloadResult, 1)
+	go func() {
+		results, err := client.WaitAll(context.Background(), gids...)
+		assert.NoError(t, err)
+		done <- results
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, server.FailDownload(gids[1], arigo.NetworkError, "connection reset"))
+	require.NoError(t, client.Remove(gids[2]))
+	for _, gid := range gids[3:] {
+		require.NoError(t, server.CompleteDownload(gid))
+	}
+
+	select {
+	case results := <-done:
+		require.Len(t, results, len(gids))
+
+		assert.Equal(t, arigo.DownloadResult{GID: gids[0]}, results[0])
+		assert.Equal(t, arigo.DownloadResult{
+			GID: gids[1],
+			Err: &arigo.DownloadError{
+				GID:          gids[1],
+				ExitStatus:   arigo.NetworkError,
+				ErrorMessage: "connection reset",
+			},
+			ErrorCode:    arigo.NetworkError,
+			ErrorMessage: "connection reset",
+		}, results[1])
+		assert.Equal(t, arigo.DownloadResult{GID: gids[2], Err: arigo.ErrDownloadStopped}, results[2])
+
+		for i, result := range results[3:] {
+			assert.Equal(t, arigo.DownloadResult{GID: gids[i+3]}, result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitAll didn't return")
+	}
+}
+
+func TestWaitAllUnknownGID(t *testing.T) {
+	server, client := newTestClient(t)
+
+	gid, err := client.AddURI([]string{"https://example.org"}, nil)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		assert.NoError(t, server.CompleteDownload(gid.GID))
+	}()
+
+	// a download aria2 doesn't know doesn't stop the others from being waited for
+	results, err := client.WaitAll(context.Background(), "00000000000000ff", gid.GID)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "00000000000000ff", results[0].GID)
+	assert.True(t, errors.Is(results[0].Err, arigo.ErrGIDNotFound))
+	assert.Equal(t, arigo.DownloadResult{GID: gid.GID}, results[1])
+}
+
+func TestWaitAny(t *testing.T) {
+	server, client := newTestClient(t)
+
+	first, err := client.AddURI([]string{"https://example.org"}, nil)
+	require.NoError(t, err)
+	second, err := client.AddURI([]string{"https://example.org"}, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = client.WaitAny(ctx, first.GID, second.GID)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		assert.NoError(t, server.FailDownload(second.GID, arigo.ResourceNotFound, "not found"))
+	}()
+
+	result, err := client.WaitAny(context.Background(), first.GID, second.GID)
+	require.NoError(t, err)
+	assert.Equal(t, second.GID, result.GID)
+	assert.True(t, errors.Is(result.Err, arigo.ErrDownloadError))
+	assert.Equal(t, arigo.ResourceNotFound, result.ErrorCode)
+
+	_, err = client.WaitAny(context.Background())
+	assert.Equal(t, arigo.ErrNoDownloads, err)
+}